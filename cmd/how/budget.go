@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/swibrow/how/internal/budget"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+)
+
+// budgeted is a Provider that checks the budget before every request it
+// sends, so repairs, regenerations and reviews count as well as the first
+// answer. Once a limit is reached it switches to budget.fallback for the
+// rest of the run, or refuses the request if none is set.
+type budgeted struct {
+	store    *memory.Store
+	cfg      *config.Config
+	provider llm.Provider
+	// fallback is the budget.fallback provider, once switched to.
+	fallback llm.Provider
+}
+
+// withBudget wraps provider, built from cfg, to enforce the budget.
+func withBudget(store *memory.Store, cfg *config.Config, provider llm.Provider) *budgeted {
+	return &budgeted{store: store, cfg: cfg, provider: provider}
+}
+
+// fellBack reports whether answers now come from budget.fallback.
+func (b *budgeted) fellBack() bool {
+	return b.fallback != nil
+}
+
+func (b *budgeted) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p, err := b.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Complete(ctx, req)
+	return b.label(resp), err
+}
+
+// Stream implements StreamingProvider, calling Complete on providers that
+// cannot stream.
+func (b *budgeted) Stream(ctx context.Context, req llm.Request, onChunk func(string)) (*llm.Response, error) {
+	p, err := b.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sp, ok := p.(llm.StreamingProvider)
+	if !ok {
+		resp, err := p.Complete(ctx, req)
+		return b.label(resp), err
+	}
+	resp, err := sp.Stream(ctx, req, onChunk)
+	return b.label(resp), err
+}
+
+// label marks a response from the fallback with its provider name, so its
+// usage is not recorded against the cloud provider it replaced.
+func (b *budgeted) label(resp *llm.Response) *llm.Response {
+	if resp != nil && b.fallback != nil && resp.Provider == "" {
+		resp.Provider = b.cfg.Budget.Fallback
+	}
+	return resp
+}
+
+// check returns the provider to send the next request to.
+func (b *budgeted) check(ctx context.Context) (llm.Provider, error) {
+	if b.fallback != nil {
+		return b.fallback, nil
+	}
+	if !budget.Applies(b.cfg) {
+		return b.provider, nil
+	}
+
+	var reason error
+	if b.store == nil {
+		reason = errBudgetUnavailable
+	} else if models := budget.Unpriced(b.cfg); len(models) > 0 {
+		reason = &budget.UnpricedError{Models: models}
+	} else {
+		now := time.Now()
+		rows, err := b.store.Usage(ctx, budget.Since(now))
+		if err != nil {
+			return nil, fmt.Errorf("checking budget: %w", err)
+		}
+		reason = budget.Check(b.cfg, append(rows, b.raceRows(now)...), now)
+	}
+	if reason == nil {
+		return b.provider, nil
+	}
+	if b.cfg.Budget.Fallback == "" {
+		return nil, budgetError{fmt.Errorf("%w; raise the limit or set budget.fallback in ~/.config/how/config.yaml", reason)}
+	}
+
+	local := *b.cfg
+	local.Provider = b.cfg.Budget.Fallback
+	local.Providers = nil
+	fallback, err := llm.NewProvider(&local)
+	if err != nil {
+		return nil, budgetError{fmt.Errorf("%w; budget fallback %s: %w", reason, local.Provider, err)}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v; using %s\n", reason, local.Provider)
+	b.fallback = fallback
+	return fallback, nil
+}
+
+// raceRows returns a pending request for each cloud provider in a race
+// beyond the first, since a race sends every participant a request.
+func (b *budgeted) raceRows(now time.Time) []memory.UsageRow {
+	race, ok := b.provider.(*llm.Race)
+	if !ok {
+		return nil
+	}
+	var rows []memory.UsageRow
+	first := true
+	for _, name := range race.Names() {
+		if llm.IsLocal(b.cfg, name) {
+			continue
+		}
+		if first {
+			first = false
+			continue
+		}
+		rows = append(rows, pendingRow(b.cfg, name, now))
+	}
+	return rows
+}
+
+// pendingRow counts one request about to be sent to the named provider.
+func pendingRow(cfg *config.Config, name string, now time.Time) memory.UsageRow {
+	return memory.UsageRow{
+		Day:      now.Format("2006-01-02"),
+		Provider: name,
+		Model:    llm.ModelName(cfg, name),
+		Requests: 1,
+	}
+}
+
+// budgetError is a request the budget refused to send.
+type budgetError struct {
+	error
+}
+
+func (e budgetError) Unwrap() error {
+	return e.error
+}
+
+// errBudgetUnavailable means usage cannot be counted, so no cloud request
+// is sent while a limit is configured.
+var errBudgetUnavailable = errors.New("budget cannot be enforced: memory database unavailable")
+
+// budgetSkips returns, for each compare target, the budget error that rules
+// it out, or nil. Local targets always run. Cloud targets are admitted in
+// order while the budget has room, each counting as one more request, so
+// that comparing cannot go far past a limit. Like a budgeted provider, it
+// rules out cloud targets whose usage or spend cannot be counted.
+func budgetSkips(ctx context.Context, store *memory.Store, cfg *config.Config, targets []string) ([]error, error) {
+	skips := make([]error, len(targets))
+	if !cfg.Budget.Enabled() {
+		return skips, nil
+	}
+
+	now := time.Now()
+	var rows []memory.UsageRow
+	if store != nil {
+		var err error
+		if rows, err = store.Usage(ctx, budget.Since(now)); err != nil {
+			return nil, fmt.Errorf("checking budget: %w", err)
+		}
+	}
+	for i, target := range targets {
+		tcfg := targetConfig(cfg, target)
+		if tcfg == nil || llm.IsLocal(cfg, tcfg.Provider) {
+			continue
+		}
+		if store == nil {
+			skips[i] = fmt.Errorf("skipped: %w", errBudgetUnavailable)
+			continue
+		}
+		if models := budget.Unpriced(tcfg); len(models) > 0 {
+			skips[i] = fmt.Errorf("skipped: %w", &budget.UnpricedError{Models: models})
+			continue
+		}
+		if exceeded := budget.Check(cfg, rows, now); exceeded != nil {
+			skips[i] = fmt.Errorf("skipped: %w", exceeded)
+			continue
+		}
+		rows = append(rows, pendingRow(tcfg, tcfg.Provider, now))
+	}
+	return skips, nil
+}
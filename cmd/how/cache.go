@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+)
+
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the response cache",
+	}
+
+	cacheClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear all cached responses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openMemoryStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close() //nolint:errcheck
+
+			if err := store.CacheClear(context.Background()); err != nil {
+				return fmt.Errorf("clearing cache: %w", err)
+			}
+			fmt.Println("Cache cleared.")
+			return nil
+		},
+	}
+
+	cacheCmd.AddCommand(cacheClearCmd)
+	return cacheCmd
+}
+
+// responseCacheKey keys a request on the configured provider(s) and
+// model(s), the system prompt and the question. system replaces the
+// request's system prompt, so that the memory section and other context
+// that changes between identical questions can be left out of the key.
+func responseCacheKey(cfg *config.Config, system string, req llm.Request) string {
+	names := cfg.Providers
+	if len(names) == 0 {
+		names = []string{cfg.Provider}
+	}
+	models := make([]string, len(names))
+	for i, name := range names {
+		models[i] = llm.ModelName(cfg, name)
+	}
+
+	req.System = system
+	question := req.Messages[len(req.Messages)-1].Content
+	return memory.CacheKey(strings.Join(names, ","), strings.Join(models, ","), req.SystemPrompt(), question)
+}
+
+// lookupCache returns the cached response for key, or nil on a miss. Cache
+// problems are never fatal; they just fall through to the provider.
+func lookupCache(ctx context.Context, store *memory.Store, key string, ttl time.Duration) *llm.Response {
+	if store == nil || key == "" {
+		return nil
+	}
+	data, ok, err := store.CacheGet(ctx, key, ttl)
+	if err != nil || !ok {
+		return nil
+	}
+
+	var resp llm.Response
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil
+	}
+	resp.Cached = true
+	return &resp
+}
+
+// storeCache saves a response under key and drops expired entries.
+func storeCache(ctx context.Context, store *memory.Store, key string, ttl time.Duration, resp *llm.Response) {
+	if store == nil || key == "" {
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = store.CachePut(ctx, key, string(data))
+	_ = store.CachePrune(ctx, ttl)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/prompt"
+	"github.com/swibrow/how/internal/ui"
+)
+
+func newCompareCmd() *cobra.Command {
+	var targets []string
+
+	compareCmd := &cobra.Command{
+		Use:   "compare <question>",
+		Short: "Ask several providers the same question and compare their answers",
+		Long: `Ask several providers the same question in parallel and show their
+answers side by side with latency, token usage and warnings.
+
+Providers default to the providers list in the config, or the single
+configured provider. Use --providers to choose others; add :model to
+compare models of one provider, e.g. --providers ollama:llama3,ollama:qwen2.5-coder.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			if len(targets) == 0 {
+				targets = cfg.Providers
+			}
+			if len(targets) == 0 {
+				targets = []string{cfg.Provider}
+			}
+
+			store, err := openMemoryStore()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: usage will not be recorded: %v\n", err)
+				store = nil
+			} else {
+				defer store.Close() //nolint:errcheck
+			}
+
+			ctx := context.Background()
+			question := strings.Join(args, " ")
+			req := llm.NewRequest(prompt.SystemPrompt(cfg.SystemPrompt, promptContext(ctx, cfg)...), question)
+
+			skips, err := budgetSkips(ctx, store, cfg, targets)
+			if err != nil {
+				ui.DisplayError(err.Error())
+				return err
+			}
+			rows := compare(ctx, cfg, targets, skips, req)
+			ui.DisplayComparison(os.Stdout, rows)
+
+			var candidates []ui.Result
+			for _, r := range rows {
+				if r.Err != nil {
+					continue
+				}
+				candidates = append(candidates, r.Result)
+				if store != nil {
+					name, _, _ := strings.Cut(r.Target, ":")
+					model := llm.ModelName(targetConfig(cfg, r.Target), name)
+					_ = store.RecordUsage(ctx, name, model, r.Usage.InputTokens, r.Usage.OutputTokens)
+				}
+			}
+
+			if len(candidates) == 0 {
+				return fmt.Errorf("no provider answered")
+			}
+			if !ui.IsInteractive() {
+				return nil
+			}
+			chosen, ok, err := ui.Pick(candidates)
+			if err != nil || !ok {
+				return err
+			}
+			chosen.Production = productionLabel(chosen.Command)
+			ui.Display(chosen)
+			run, err := ui.Confirm("Run this command?")
+			if err != nil || !run {
+				return err
+			}
+			return ui.RunCommand(chosen.Command)
+		},
+	}
+
+	compareCmd.Flags().StringSliceVar(&targets, "providers", nil, "Providers to compare, as `name[:model],...`")
+	compareCmd.Flags().BoolVar(&flagNoContext, "no-context", false, "Don't describe the project, git repository, kubectl context or AWS profile to the model")
+	return compareCmd
+}
+
+// compare sends req to each target in parallel and returns their answers
+// in the order of targets. A target is a provider name, optionally
+// followed by :model to override its configured model. Targets with a
+// non-nil error in skips are not sent the request and show that error.
+func compare(ctx context.Context, cfg *config.Config, targets []string, skips []error, req llm.Request) []ui.Comparison {
+	rows := make([]ui.Comparison, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if skips[i] != nil {
+			rows[i] = ui.Comparison{Target: target, Err: skips[i]}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows[i] = compareOne(ctx, cfg, target, req)
+		}()
+	}
+	wg.Wait()
+	return rows
+}
+
+func compareOne(ctx context.Context, cfg *config.Config, target string, req llm.Request) ui.Comparison {
+	row := ui.Comparison{Target: target}
+	tcfg := targetConfig(cfg, target)
+	if tcfg == nil {
+		row.Err = fmt.Errorf("unknown provider: %s", target)
+		return row
+	}
+	provider, err := llm.NewProvider(tcfg)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	start := time.Now()
+	resp, err := provider.Complete(ctx, req)
+	row.Latency = time.Since(start)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	row.Usage = resp.Usage
+	row.Result = ui.NewResult(resp)
+	row.Result.Provider = target
+	row.Result.Validate()
+	return row
+}
+
+// targetConfig returns a copy of cfg that uses the provider and model
+// named by target, or nil if the provider is unknown.
+func targetConfig(cfg *config.Config, target string) *config.Config {
+	name, model, hasModel := strings.Cut(target, ":")
+	tcfg := *cfg
+	tcfg.Provider = name
+	tcfg.Providers = nil
+	if hasModel {
+		if err := llm.SetModel(&tcfg, name, model); err != nil {
+			return nil
+		}
+	}
+	return &tcfg
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/swibrow/how/internal/cloud"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/env"
+	"github.com/swibrow/how/internal/git"
+	"github.com/swibrow/how/internal/input"
+	"github.com/swibrow/how/internal/project"
+	"github.com/swibrow/how/internal/prompt"
+	"github.com/swibrow/how/internal/ui"
+)
+
+// promptContext returns the sections describing the user's machine and
+// working directory to add to the system prompt.
+func promptContext(ctx context.Context, cfg *config.Config) []string {
+	sections, _ := describeContext(ctx, cfg)
+	return sections
+}
+
+// describeContext returns the system prompt sections and a stable version
+// of them for keying the response cache, in which the git section is
+// replaced by the repository's state so edits and commits keep the key.
+func describeContext(ctx context.Context, cfg *config.Config) (sections, stable []string) {
+	repo := inspectRepo(ctx)
+	base := []string{environmentContext(cfg), projectContext(), cloudContext()}
+	sections = append(slices.Clone(base), prompt.FormatGit(repo))
+	stable = append(base, repo.State())
+	return sections, stable
+}
+
+// environmentContext describes the installed tools, shell and distribution
+// for the system prompt, using the probe cached in the config directory.
+func environmentContext(cfg *config.Config) string {
+	if !cfg.Environment.Enabled {
+		return ""
+	}
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return ""
+	}
+	tools := slices.Concat(env.Tools, cfg.Environment.Tools)
+	return prompt.FormatEnvironment(env.Load(dir, cfg.Environment.TTL, tools))
+}
+
+// projectContext summarizes the project files around the working
+// directory, unless --no-context is set.
+func projectContext() string {
+	if flagNoContext {
+		return ""
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return prompt.FormatProject(project.Detect(dir))
+}
+
+// inspectRepo reads the git repository around the working directory,
+// unless --no-context is set.
+func inspectRepo(ctx context.Context) *git.Repo {
+	if flagNoContext {
+		return nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	return git.Inspect(ctx, dir)
+}
+
+// cloudContext describes the active kubectl context and AWS profile,
+// unless --no-context is set.
+func cloudContext() string {
+	if flagNoContext {
+		return ""
+	}
+	return prompt.FormatCloud(cloud.LoadKube(), cloud.LoadAWS())
+}
+
+// productionLabel describes the production-looking kubectl contexts or
+// AWS profiles that command would act on, or returns "" if there are none.
+// Tools are found anywhere in the command, such as under watch, xargs or
+// $( ), and the --context, --namespace, --kubeconfig and --profile flags and
+// KUBECONFIG= or AWS_PROFILE= assignments they are run with are honoured.
+// It applies even with --no-context.
+func productionLabel(command string) string {
+	var labels []string
+	add := func(label string) {
+		if label != "" && !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	// exported holds variables set with export earlier in the command.
+	exported := map[string]string{}
+	for _, seg := range shellSeparators.Split(command, -1) {
+		fields := strings.Fields(seg)
+		for i, f := range fields {
+			fields[i] = strings.Trim(f, `"'`)
+		}
+
+		// Assignments before the first tool, as in "AWS_PROFILE=prod aws" or
+		// "env KUBECONFIG=... kubectl", apply to the tools that follow.
+		vars := maps.Clone(exported)
+		seenTool := false
+		for i, f := range fields {
+			if key, value, ok := strings.Cut(f, "="); ok && !seenTool && envNameRe.MatchString(key) {
+				vars[key] = value
+				if fields[0] == "export" {
+					exported[key] = value
+				}
+				continue
+			}
+			tool, args := filepath.Base(f), fields[i+1:]
+			switch {
+			case slices.Contains(cloud.KubeTools, tool):
+				add(kubeLabel(vars, args))
+				seenTool = true
+			case slices.Contains(cloud.AWSTools, tool):
+				add(awsLabel(vars, args))
+				seenTool = true
+			}
+		}
+	}
+	return strings.Join(labels, "; ")
+}
+
+// shellSeparators splits a command into the simple commands it runs,
+// including those in subshells, $( ) and backticks.
+var shellSeparators = regexp.MustCompile("\\|\\||&&|\\$\\(|[|;&()`]")
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// kubeLabel describes the kubectl context args would act on if it looks
+// like production.
+func kubeLabel(vars map[string]string, args []string) string {
+	files, ok := vars["KUBECONFIG"]
+	if !ok {
+		files = os.Getenv("KUBECONFIG")
+	}
+	if path := flagValue(args, "--kubeconfig"); path != "" {
+		files = path
+	}
+	k := cloud.LoadKubeContext(files, flagValue(args, "--context", "--kube-context"))
+	if k == nil {
+		return ""
+	}
+	if ns := flagValue(args, "--namespace", "-n"); ns != "" {
+		k.Namespace = ns
+	}
+	if !k.Production() {
+		return ""
+	}
+	return fmt.Sprintf("kubectl context %s, namespace %s", k.Context, k.Namespace)
+}
+
+// awsLabel describes the AWS profile args would use if it looks like
+// production.
+func awsLabel(vars map[string]string, args []string) string {
+	profile := flagValue(args, "--profile")
+	for _, key := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		if profile == "" {
+			profile = vars[key]
+		}
+	}
+	a := cloud.LoadAWS()
+	if profile != "" {
+		a = &cloud.AWS{Profile: profile}
+	}
+	if !a.Production() {
+		return ""
+	}
+	return "AWS profile " + a.Profile
+}
+
+// flagValue returns the value of the first of the named flags in args,
+// given as "--flag value" or "--flag=value", or "" if none is set.
+func flagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		for _, name := range names {
+			if arg == name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if value, ok := strings.CutPrefix(arg, name+"="); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// stdin is read for input piped into how. Tests replace it.
+var stdin = os.Stdin
+
+// pipedInput reads an excerpt of the input piped into how, or returns nil
+// if stdin is a terminal or the input is empty. Prompts then read from the
+// terminal, as stdin is used up. Unlike the other context it is sent even
+// with --no-context, since the user passed it explicitly.
+func pipedInput() (*input.Excerpt, error) {
+	if !input.Piped(stdin) {
+		return nil, nil
+	}
+	if !flagQuiet {
+		ui.OpenTerminal()
+	}
+	e, err := input.Read(stdin)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	verbosef("read %d bytes from stdin, sending %d with %d secrets redacted", e.Size, len(e.Text), e.Redacted)
+	return e, nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/swibrow/how/internal/config"
@@ -15,11 +17,23 @@ import (
 )
 
 var (
-	flagYes   bool
-	flagQuiet bool
+	flagYes          bool
+	flagQuiet        bool
+	flagAlternatives int
+	flagNoCache      bool
+	flagRecord       string
+	flagVerify       bool
+	flagVerbose      bool
+	flagNoContext    bool
 )
 
 func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:           "how [question]",
 		Short:         "Smart terminal cheatsheet — ask a question, get a command",
@@ -32,6 +46,12 @@ func main() {
 
 	rootCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Run the command without confirmation")
 	rootCmd.Flags().BoolVarP(&flagQuiet, "quiet", "q", false, "Output only the command (for piping)")
+	rootCmd.Flags().IntVarP(&flagAlternatives, "alternatives", "n", 0, "Ask for N alternative commands to pick from")
+	rootCmd.Flags().BoolVar(&flagNoCache, "no-cache", false, "Skip the response cache and always ask the provider")
+	rootCmd.Flags().BoolVarP(&flagVerbose, "verbose", "v", false, "Log diagnostics such as repair attempts to stderr")
+	rootCmd.Flags().BoolVar(&flagVerify, "verify", false, "Have the model critique the command and regenerate it if the review fails")
+	rootCmd.Flags().StringVar(&flagRecord, "record", "", "Record provider exchanges to a cassette `file` for the replay provider")
+	rootCmd.Flags().BoolVar(&flagNoContext, "no-context", false, "Don't describe the project, git repository, kubectl context or AWS profile to the model")
 
 	configCmd := &cobra.Command{
 		Use:   "config",
@@ -121,11 +141,8 @@ func main() {
 
 	memoryCmd.AddCommand(memoryListCmd, memoryClearCmd)
 	configCmd.AddCommand(configShowCmd, configInitCmd)
-	rootCmd.AddCommand(configCmd, memoryCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
-	}
+	rootCmd.AddCommand(configCmd, memoryCmd, newCacheCmd(), newStatsCmd(), newCompareCmd(), newModelsCmd())
+	return rootCmd
 }
 
 func openMemoryStore() (*memory.Store, error) {
@@ -149,21 +166,29 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Open memory store (non-fatal on failure)
-	var store *memory.Store
-	if cfg.Memory.Enabled {
-		store, err = openMemoryStore()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: memory disabled: %v\n", err)
-		} else {
-			defer store.Close() //nolint:errcheck
-		}
+	// Open memory store (non-fatal on failure). It also holds the
+	// response cache and usage records.
+	useCache := cfg.Cache.Enabled && !flagNoCache && flagRecord == ""
+	store, err := openMemoryStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: memory disabled: %v\n", err)
+		store = nil
+	} else {
+		defer store.Close() //nolint:errcheck
+	}
+	remember := cfg.Memory.Enabled && store != nil
+
+	piped, err := pipedInput()
+	if err != nil {
+		ui.DisplayError(err.Error())
+		return err
 	}
 
 	// Build system prompt, enriching with memory context if available
 	ctx := context.Background()
-	sysPrompt := prompt.SystemPrompt(cfg.SystemPrompt)
-	if store != nil {
+	sections, stable := describeContext(ctx, cfg)
+	sysPrompt := prompt.SystemPrompt(cfg.SystemPrompt, sections...)
+	if remember {
 		if past, err := store.Search(ctx, question, 10); err == nil && len(past) > 0 {
 			sysPrompt += prompt.FormatMemoryContext(past)
 		}
@@ -174,41 +199,185 @@ func run(cmd *cobra.Command, args []string) error {
 		ui.DisplayError(fmt.Sprintf("initializing provider: %v", err))
 		return err
 	}
-
-	response, err := provider.Complete(ctx, sysPrompt, question)
-	if err != nil {
-		ui.DisplayError(fmt.Sprintf("LLM request failed: %v", err))
-		return err
+	if !flagQuiet && ui.IsInteractive() {
+		if err := ensureOllamaModel(ctx, cfg); err != nil {
+			ui.DisplayError(err.Error())
+			return err
+		}
+	}
+	if p, ok := provider.(interface{ Skipped() []error }); ok {
+		for _, err := range p.Skipped() {
+			fmt.Fprintf(os.Stderr, "Warning: skipping provider %v\n", err)
+		}
+	}
+	switch p := provider.(type) {
+	case *llm.Fallback:
+		p.OnFailover = func(name string, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %s failed, trying next provider: %v\n", name, err)
+		}
+	case *llm.Race:
+		p.Valid = func(r *llm.Response) bool { return ui.NewResult(r).Command != "" }
+		p.OnWin = func(name string, elapsed time.Duration) {
+			verbosef("%s won the race in %s", name, elapsed.Round(time.Millisecond))
+			if store != nil {
+				_ = store.RecordRace(ctx, name, p.Names())
+			}
+		}
+		p.OnUsage = func(name string, usage llm.Usage) {
+			recordUsage(ctx, store, cfg, name, &llm.Response{Usage: usage})
+		}
 	}
 
-	result := ui.ParseResponse(response)
-	if result.Command == "" {
-		ui.DisplayError("could not parse a command from the response")
-		return fmt.Errorf("no command in response")
+	// Every request, including repairs and self-reviews, is checked
+	// against the budget.
+	budgetProvider := withBudget(store, cfg, provider)
+	provider = budgetProvider
+
+	verifyOn := flagVerify || cfg.Verify.Enabled
+	var rv *reviewer
+	var reviewPrompt string
+	if verifyOn {
+		reviewPrompt = prompt.ReviewPrompt(sections...)
+		if rv, err = newReviewer(cfg, store); err != nil {
+			ui.DisplayError(err.Error())
+			return err
+		}
 	}
 
-	if missing := ui.ValidateCommand(result.Command); len(missing) > 0 && !flagQuiet {
-		ui.DisplayWarnings(missing)
+	// The conversation grows with each refinement so the model can correct
+	// its previous answer instead of starting over.
+	req := llm.NewRequest(sysPrompt, prompt.FormatInput(question, piped))
+	req.Alternatives = cfg.Alternatives
+	if cmd.Flags().Changed("alternatives") {
+		req.Alternatives = flagAlternatives
 	}
 
-	if flagQuiet {
-		ui.DisplayQuiet(result)
-		return nil
+	// Only the initial question is cached; refinements depend on the
+	// conversation so far.
+	var cacheKey string
+	if useCache && store != nil {
+		cacheKey = responseCacheKey(cfg, prompt.SystemPrompt(cfg.SystemPrompt, stable...), req)
 	}
 
-	ui.Display(result)
+	// asked is the question with any piped input and refinements, as shown
+	// to the reviewer.
+	asked := req.Messages[0].Content
+	for {
+		response := lookupCache(ctx, store, cacheKey, cfg.Cache.TTL)
+		if response == nil {
+			response, err = complete(ctx, recording(provider), req)
+			if err != nil {
+				displayRunError(err)
+				return err
+			}
+			recordUsage(ctx, store, cfg, cfg.Provider, response)
+		}
 
-	if flagYes {
-		err := ui.RunCommand(result.Command)
-		if err == nil && store != nil {
+		response, result, err := parseResponse(ctx, cfg, store, recording(provider), req, response)
+		if err != nil {
+			displayRunError(err)
+			return err
+		}
+		if verifyOn {
+			response, result, err = verify(ctx, cfg, store, rv, reviewPrompt, recording(provider), &req, asked, response, result)
+			if err != nil {
+				displayRunError(err)
+				return err
+			}
+			if flagQuiet && !result.Verdict.Passed {
+				ui.DisplayError(fmt.Sprintf("command failed review: %s", result.Verdict.Critique))
+				return fmt.Errorf("command failed review")
+			}
+		}
+		// A command that still fails review is not cached, or a later run
+		// without --verify would return it unchecked. Nor is an answer from
+		// the budget fallback, which the key does not describe.
+		if !response.Cached && (result.Verdict == nil || result.Verdict.Passed) && !budgetProvider.fellBack() {
+			storeCache(ctx, store, cacheKey, cfg.Cache.TTL, response)
+		}
+		cacheKey = ""
+
+		if flagQuiet {
+			ui.DisplayQuiet(result)
+			return nil
+		}
+
+		result.Validate()
+		if len(result.Alternatives) > 0 && !flagYes && ui.IsInteractive() {
+			chosen, ok, err := ui.Pick(result.Candidates())
+			if err != nil || !ok {
+				return err
+			}
+			if verifyOn {
+				if chosen, err = verifyChoice(ctx, cfg, store, rv, reviewPrompt, recording(provider), asked, chosen); err != nil {
+					displayRunError(err)
+					return err
+				}
+			}
+			result = chosen
+		}
+
+		if len(result.Missing) > 0 {
+			ui.DisplayWarnings(result.Missing)
+		}
+		result.Production = productionLabel(result.Command)
+		ui.Display(result)
+
+		if flagYes {
+			err := ui.RunCommand(result.Command)
+			if err == nil && remember {
+				_ = store.Save(ctx, question, result.Command, result.Explanation)
+			}
+			return err
+		}
+
+		choice, err := ui.ConfirmAndRun(result.Command)
+		if choice == ui.ChoiceRun && err == nil && remember {
 			_ = store.Save(ctx, question, result.Command, result.Explanation)
 		}
-		return err
+		if choice != ui.ChoiceRefine {
+			return err
+		}
+
+		refinement, err := ui.ReadRefinement()
+		if err != nil || refinement == "" {
+			return err
+		}
+		req.Messages = append(req.Messages,
+			llm.Message{Role: llm.RoleAssistant, Content: prompt.FormatAnswer(result.Command, result.Explanation)},
+			llm.Message{Role: llm.RoleUser, Content: refinement},
+		)
+		asked += "\nRefinement: " + refinement
 	}
+}
+
+// displayRunError shows a failed request, with a hint for provider errors.
+func displayRunError(err error) {
+	var berr budgetError
+	if errors.Is(err, errNoCommand) || errors.As(err, &berr) {
+		ui.DisplayError(err.Error())
+		return
+	}
+	ui.DisplayProviderError(err)
+}
 
-	confirmed, err := ui.ConfirmAndRun(result.Command)
-	if confirmed && err == nil && store != nil {
-		_ = store.Save(ctx, question, result.Command, result.Explanation)
+// recording wraps provider to save its exchanges when --record is set.
+func recording(provider llm.Provider) llm.Provider {
+	if flagRecord == "" {
+		return provider
 	}
-	return err
+	return llm.NewRecorder(provider, flagRecord)
+}
+
+// complete sends the request to the provider, streaming the command to the
+// terminal as it arrives when both the provider and the output support it.
+func complete(ctx context.Context, provider llm.Provider, req llm.Request) (*llm.Response, error) {
+	sp, ok := provider.(llm.StreamingProvider)
+	if !ok || flagQuiet || !ui.IsInteractive() {
+		return provider.Complete(ctx, req)
+	}
+
+	renderer := ui.NewStreamRenderer(os.Stdout)
+	defer renderer.Finish()
+	return sp.Stream(ctx, req, renderer.Write)
 }
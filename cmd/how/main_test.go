@@ -0,0 +1,600 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/input"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+	"github.com/swibrow/how/internal/prompt"
+	"github.com/swibrow/how/internal/ui"
+)
+
+// setupReplay points the config at a temp directory using the replay
+// provider with the given cassette.
+func setupReplay(t *testing.T, cassette *llm.Cassette) {
+	t.Helper()
+	dir := t.TempDir()
+	config.ConfigDirFunc = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { config.ConfigDirFunc = nil })
+
+	path := filepath.Join(dir, "cassette.yaml")
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("saving cassette: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "replay"
+	cfg.Replay.Cassette = path
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("saving config: %v", err)
+	}
+	setStdin(t, os.DevNull)
+}
+
+// setStdin makes the CLI read piped input from the file at path.
+func setStdin(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := stdin
+	stdin = f
+	t.Cleanup(func() {
+		stdin = saved
+		f.Close() //nolint:errcheck
+	})
+}
+
+// execute runs the CLI with args and returns what it wrote to stdout.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	cmd := newRootCmd()
+	cmd.SetArgs(args)
+	runErr := cmd.Execute()
+
+	w.Close() //nolint:errcheck
+	out, _ := io.ReadAll(r)
+	return string(out), runErr
+}
+
+func TestRunReplayQuiet(t *testing.T) {
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", "list listening ports"), &llm.Response{
+		Command:     "ss -ltnp",
+		Explanation: "Show listening TCP sockets with their processes",
+	})
+	setupReplay(t, cassette)
+
+	out, err := execute(t, "-q", "list", "listening", "ports")
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.TrimSpace(out) != "ss -ltnp" {
+		t.Errorf("output: got %q, want %q", out, "ss -ltnp")
+	}
+}
+
+func TestRunPipedInput(t *testing.T) {
+	log := "connecting with password=hunter2\nERROR: dial tcp 10.0.0.5:5432: connection refused\n"
+	excerpt, err := input.Read(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	question := "why does this fail"
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", prompt.FormatInput(question, excerpt)), &llm.Response{
+		Command:     "pg_isready -h 10.0.0.5 -p 5432",
+		Explanation: "Check whether PostgreSQL accepts connections",
+	})
+	setupReplay(t, cassette)
+
+	path := filepath.Join(t.TempDir(), "error.log")
+	if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	setStdin(t, path)
+
+	out, err := execute(t, "-q", "why", "does", "this", "fail")
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.TrimSpace(out) != "pg_isready -h 10.0.0.5 -p 5432" {
+		t.Errorf("output: got %q", out)
+	}
+	if strings.Contains(prompt.FormatInput(question, excerpt), "hunter2") {
+		t.Error("password was sent to the provider")
+	}
+}
+
+func TestRunReplayMiss(t *testing.T) {
+	setupReplay(t, &llm.Cassette{})
+
+	if _, err := execute(t, "-q", "something never recorded"); err == nil {
+		t.Fatal("expected an error for a question missing from the cassette")
+	}
+}
+
+func TestRunVerifyRegenerates(t *testing.T) {
+	question := "show disk usage of this folder"
+	bad := &llm.Response{Command: "du --depth-human .", Explanation: "Disk usage", Text: "COMMAND: du --depth-human .\nEXPLANATION: Disk usage"}
+	good := &llm.Response{Command: "du -sh .", Explanation: "Total size of the folder", Text: "COMMAND: du -sh .\nEXPLANATION: Total size of the folder"}
+	rejected := &llm.Response{Command: "FAIL", Explanation: "du has no --depth-human flag", Warnings: []string{"--depth-human does not exist"}}
+
+	first := llm.NewRequest("", question)
+	retry := first
+	retry.Messages = append(retry.Messages,
+		llm.Message{Role: llm.RoleAssistant, Content: bad.Text},
+		llm.Message{Role: llm.RoleUser, Content: prompt.FormatReviewFeedback(rejected.Explanation, rejected.Warnings)},
+	)
+
+	cassette := &llm.Cassette{}
+	cassette.Add(first, bad)
+	cassette.Add(llm.NewRequest("", prompt.FormatReview(question, bad.Command, bad.Explanation)), rejected)
+	cassette.Add(retry, good)
+	cassette.Add(llm.NewRequest("", prompt.FormatReview(question, good.Command, good.Explanation)),
+		&llm.Response{Command: "PASS", Explanation: "Correct and portable"})
+	setupReplay(t, cassette)
+
+	out, err := execute(t, "-q", "--verify", question)
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.TrimSpace(out) != "du -sh ." {
+		t.Errorf("output: got %q, want the regenerated command", out)
+	}
+}
+
+func TestRunVerifyQuietRefusesFailedCommand(t *testing.T) {
+	question := "delete everything"
+	answer := &llm.Response{Command: "rm -rf /", Explanation: "Delete all files"}
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", question), answer)
+	cassette.Add(llm.NewRequest("", prompt.FormatReview(question, answer.Command, answer.Explanation)),
+		&llm.Response{Command: "FAIL", Explanation: "Destroys the system"})
+	setupReplay(t, cassette)
+
+	// Regenerate is set to zero so the first failed review is final.
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Verify.Regenerate = 0
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execute(t, "-q", "--verify", question)
+	if err == nil {
+		t.Fatal("expected an error for a command that failed review")
+	}
+	if out != "" {
+		t.Errorf("no command should be printed, got %q", out)
+	}
+}
+
+func TestRunRepairsUnparseableResponse(t *testing.T) {
+	question := "count lines in main.go"
+	garbled := &llm.Response{Text: "EXPLANATION: wc counts lines"}
+
+	first := llm.NewRequest("", question)
+	repair := first
+	repair.Messages = append(repair.Messages,
+		llm.Message{Role: llm.RoleAssistant, Content: garbled.Text},
+		llm.Message{Role: llm.RoleUser, Content: prompt.FormatRepair()},
+	)
+
+	cassette := &llm.Cassette{}
+	cassette.Add(first, garbled)
+	cassette.Add(repair, &llm.Response{Text: "COMMAND: wc -l main.go\nEXPLANATION: Count lines"})
+	setupReplay(t, cassette)
+
+	out, err := execute(t, "-q", question)
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.TrimSpace(out) != "wc -l main.go" {
+		t.Errorf("output: got %q, want the repaired command", out)
+	}
+}
+
+func TestRunRepairGivesUp(t *testing.T) {
+	question := "count lines in main.go"
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", question), &llm.Response{Text: "EXPLANATION: wc counts lines"})
+	setupReplay(t, cassette)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.RepairAttempts = 0
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execute(t, "-q", question); err == nil {
+		t.Fatal("expected an error when the response has no command")
+	}
+}
+
+func TestCompareShowsEachProvider(t *testing.T) {
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", "list listening ports"), &llm.Response{
+		Command:     "ss -ltnp",
+		Explanation: "Show listening TCP sockets",
+	})
+	setupReplay(t, cassette)
+
+	out, err := execute(t, "compare", "--providers", "replay,bogus", "list", "listening", "ports")
+	if err != nil {
+		t.Fatalf("compare error: %v", err)
+	}
+	for _, want := range []string{"ss -ltnp", "replay", "unknown provider: bogus"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCompareNoAnswers(t *testing.T) {
+	setupReplay(t, &llm.Cassette{})
+
+	if _, err := execute(t, "compare", "--providers", "replay", "something never recorded"); err == nil {
+		t.Fatal("expected an error when no provider answers")
+	}
+}
+
+func TestModelsSet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3:latest"},{"name":"qwen2.5-coder:7b"}]}`)
+	}))
+	defer srv.Close()
+
+	setupReplay(t, &llm.Cassette{})
+	cfg, err := config.LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Ollama.URL = srv.URL
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+
+	out, err := execute(t, "models", "ollama")
+	if err != nil {
+		t.Fatalf("models error: %v", err)
+	}
+	if !strings.Contains(out, "llama3:latest (configured)") {
+		t.Errorf("expected llama3 to be marked as configured:\n%s", out)
+	}
+
+	if _, err := execute(t, "models", "ollama", "--set", "mistral"); err == nil {
+		t.Error("expected an error for a model the provider does not offer")
+	}
+	if _, err := execute(t, "models", "ollama", "--set", "qwen2.5-coder:7b"); err != nil {
+		t.Fatalf("models --set error: %v", err)
+	}
+
+	saved, err := config.LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Ollama.Model != "qwen2.5-coder:7b" {
+		t.Errorf("ollama model: got %q, want qwen2.5-coder:7b", saved.Ollama.Model)
+	}
+	if saved.Anthropic.APIKey != "" {
+		t.Error("API key from the environment was written to the config file")
+	}
+}
+
+func TestProductionLabel(t *testing.T) {
+	dir := t.TempDir()
+	contexts := `contexts:
+- name: kind-dev
+  context:
+    cluster: kind-dev
+- name: eks-prod
+  context:
+    cluster: prod
+`
+	staging := filepath.Join(dir, "staging")
+	prod := filepath.Join(dir, "prod")
+	for path, current := range map[string]string{staging: "kind-dev", prod: "eks-prod"} {
+		if err := os.WriteFile(path, []byte("current-context: "+current+"\n"+contexts), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("KUBECONFIG", prod)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "missing"))
+	t.Setenv("AWS_PROFILE", "staging")
+
+	const prodKube = "kubectl context eks-prod, namespace default"
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"kubectl get pods -A | grep api", prodKube},
+		{"aws s3 ls", ""},
+		{"ls -la", ""},
+		{"watch kubectl get pods", prodKube},
+		{"kubectl get pods -o name | xargs kubectl delete", prodKube},
+		{"echo $(kubectl get ns)", prodKube},
+		{"kubectl --context kind-dev get pods", ""},
+		{"KUBECONFIG=" + staging + " kubectl get pods", ""},
+		{"kubectl --kubeconfig=" + staging + " -n live get pods", "kubectl context kind-dev, namespace live"},
+		{"AWS_PROFILE=prod aws s3 ls", "AWS profile prod"},
+		{"aws --profile=prod-admin s3 ls", "AWS profile prod-admin"},
+		{"export AWS_PROFILE=prod && aws s3 ls", "AWS profile prod"},
+		{"kubectl set env deploy/api AWS_PROFILE=prod --context kind-dev", ""},
+	}
+	for _, tc := range tests {
+		if got := productionLabel(tc.command); got != tc.want {
+			t.Errorf("productionLabel(%q) = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+
+	t.Setenv("KUBECONFIG", staging)
+	if got := productionLabel("kubectl --context eks-prod get pods"); got != prodKube {
+		t.Errorf("expected --context to select eks-prod, got %q", got)
+	}
+}
+
+func TestResponseCacheKeyIgnoresMemory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	system := prompt.SystemPrompt("")
+	withMemory := system + prompt.FormatMemoryContext([]memory.Interaction{{Question: "q", Command: "ls", UseCount: 3}})
+
+	key := responseCacheKey(cfg, system, llm.NewRequest(withMemory, "list files"))
+	if other := responseCacheKey(cfg, system, llm.NewRequest(system, "list files")); key != other {
+		t.Error("expected the memory section to be left out of the key")
+	}
+
+	req := llm.NewRequest(withMemory, "list files")
+	req.Alternatives = 2
+	if responseCacheKey(cfg, system, req) == key {
+		t.Error("expected alternatives to change the key")
+	}
+}
+
+func TestRunVerifyFailureNotCached(t *testing.T) {
+	question := "print nothing"
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", question), &llm.Response{Command: "true", Explanation: "Do nothing"})
+	cassette.Add(llm.NewRequest("", prompt.FormatReview(question, "true", "Do nothing")),
+		&llm.Response{Command: "FAIL", Explanation: "Prints nothing, but not on purpose"})
+	setupReplay(t, cassette)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Verify.Regenerate = 0
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execute(t, "-y", "--verify", question); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+
+	// A fresh answer shows that the rejected one was not cached.
+	fresh := &llm.Cassette{}
+	fresh.Add(llm.NewRequest("", question), &llm.Response{Command: ":", Explanation: "Do nothing"})
+	if err := fresh.Save(cfg.Replay.Cassette); err != nil {
+		t.Fatal(err)
+	}
+	out, err := execute(t, "-q", question)
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.TrimSpace(out) != ":" {
+		t.Errorf("output: got %q, want the provider's answer rather than the rejected cached one", out)
+	}
+}
+
+func TestVerifyChoiceReviewsAlternative(t *testing.T) {
+	question := "count lines"
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", prompt.FormatReview(question, "wc -l", "Count lines")),
+		&llm.Response{Command: "PASS", Explanation: "Correct"})
+	setupReplay(t, cassette)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := llm.NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	chosen, err := verifyChoice(ctx, cfg, nil, nil, prompt.ReviewPrompt(), provider, question, ui.Result{Command: "wc -l", Explanation: "Count lines"})
+	if err != nil {
+		t.Fatalf("verifyChoice error: %v", err)
+	}
+	if chosen.Verdict == nil || !chosen.Verdict.Passed {
+		t.Errorf("expected the alternative to be reviewed, got %+v", chosen.Verdict)
+	}
+
+	// The primary candidate was already reviewed and is not sent again.
+	reviewed := ui.Result{Command: "nl", Verdict: &ui.Verdict{Passed: true}}
+	if _, err := verifyChoice(ctx, cfg, nil, nil, prompt.ReviewPrompt(), provider, question, reviewed); err != nil {
+		t.Errorf("expected no second review, got %v", err)
+	}
+}
+
+func TestCompareRespectsBudget(t *testing.T) {
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", "list listening ports"), &llm.Response{Command: "ss -ltnp"})
+	setupReplay(t, cassette)
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Budget.DailyRequests = 2
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	store, err := openMemoryStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordUsage(context.Background(), "anthropic", "claude-sonnet-4-6", 100, 10); err != nil {
+		t.Fatal(err)
+	}
+	store.Close() //nolint:errcheck
+
+	out, err := execute(t, "compare", "--providers", "replay,openai,anthropic", "list", "listening", "ports")
+	if err != nil {
+		t.Fatalf("compare error: %v", err)
+	}
+	// openai takes the last request of the day; anthropic is not sent one.
+	if !strings.Contains(out, "ss -ltnp") {
+		t.Errorf("expected the local provider to answer:\n%s", out)
+	}
+	if strings.Count(out, "daily budget of 2 requests reached") != 1 {
+		t.Errorf("expected only anthropic to be skipped:\n%s", out)
+	}
+}
+
+// unreachable is a provider that fails the test if it is sent a request.
+type unreachable struct {
+	t *testing.T
+}
+
+func (u unreachable) Complete(context.Context, llm.Request) (*llm.Response, error) {
+	u.t.Error("request sent past the budget")
+	return nil, fmt.Errorf("unreachable")
+}
+
+func TestBudgetFallsBackForEveryRequest(t *testing.T) {
+	cassette := &llm.Cassette{}
+	cassette.Add(llm.NewRequest("", "list files"), &llm.Response{Command: "ls"})
+	cassette.Add(llm.NewRequest("", prompt.FormatReview("list files", "ls", "")), &llm.Response{Command: "PASS"})
+	setupReplay(t, cassette)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Provider = "anthropic"
+	cfg.Budget.DailyRequests = 1
+	cfg.Budget.Fallback = "replay"
+	store, err := openMemoryStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close() //nolint:errcheck
+	ctx := context.Background()
+	if err := store.RecordUsage(ctx, "anthropic", cfg.Anthropic.Model, 100, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := withBudget(store, cfg, unreachable{t})
+	for _, q := range []string{"list files", prompt.FormatReview("list files", "ls", "")} {
+		resp, err := provider.Complete(ctx, llm.NewRequest("", q))
+		if err != nil {
+			t.Fatalf("Complete error: %v", err)
+		}
+		if resp.Provider != "replay" {
+			t.Errorf("Provider = %q, want the fallback recorded as answering", resp.Provider)
+		}
+	}
+	if !provider.fellBack() {
+		t.Error("expected fellBack to report the switch")
+	}
+}
+
+func TestBudgetRefuses(t *testing.T) {
+	store := func(t *testing.T) *memory.Store {
+		config.ConfigDirFunc = func() (string, error) { return t.TempDir(), nil }
+		t.Cleanup(func() { config.ConfigDirFunc = nil })
+		store, err := openMemoryStore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() }) //nolint:errcheck
+		if err := store.RecordUsage(context.Background(), "anthropic", "claude-sonnet-4-6", 100, 10); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+
+	cases := []struct {
+		name  string
+		store func(*testing.T) *memory.Store
+		setup func(*config.Config)
+		want  string
+	}{
+		{
+			name:  "no memory database",
+			store: func(*testing.T) *memory.Store { return nil },
+			setup: func(cfg *config.Config) { cfg.Budget.DailyRequests = 100 },
+			want:  "memory database unavailable",
+		},
+		{
+			name:  "unpriced model",
+			store: store,
+			setup: func(cfg *config.Config) { cfg.Budget.MonthlyCost = 20 },
+			want:  "no price for " + config.DefaultConfig().Anthropic.Model,
+		},
+		{
+			name:  "race past the limit",
+			store: store,
+			setup: func(cfg *config.Config) {
+				cfg.Budget.DailyRequests = 2
+				cfg.Providers = []string{"anthropic", "openai"}
+				cfg.Strategy = "race"
+				cfg.Anthropic.APIKey = "key"
+				cfg.OpenAI.APIKey = "key"
+			},
+			want: "daily budget of 2 requests reached (2 used)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			tc.setup(cfg)
+			var inner llm.Provider = unreachable{t}
+			if len(cfg.Providers) > 0 {
+				p, err := llm.NewProvider(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inner = p
+			}
+
+			_, err := withBudget(tc.store(t), cfg, inner).Complete(context.Background(), llm.NewRequest("", "q"))
+			var berr budgetError
+			if !errors.As(err, &berr) || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected a budget error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
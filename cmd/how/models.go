@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/ui"
+)
+
+func newModelsCmd() *cobra.Command {
+	var set string
+
+	modelsCmd := &cobra.Command{
+		Use:   "models [provider]",
+		Short: "List the models a provider offers",
+		Long: `List the models a provider offers, marking the configured one.
+Without a provider, the configured provider (or each in the providers
+list) is shown. Use --set to save a model to the config file.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+
+			names := []string{cfg.Provider}
+			switch {
+			case len(args) == 1:
+				names = args
+			case len(cfg.Providers) > 0:
+				names = cfg.Providers
+			}
+
+			if set != "" {
+				if len(names) != 1 {
+					return fmt.Errorf("name the provider to set the model for, e.g. how models %s --set %s", names[0], set)
+				}
+				if err := setModel(context.Background(), cfg, names[0], set); err != nil {
+					ui.DisplayError(err.Error())
+					return err
+				}
+				return nil
+			}
+
+			ctx := context.Background()
+			var failed error
+			for i, name := range names {
+				if len(names) > 1 {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("%s:\n", name)
+				}
+				models, err := llm.ListModels(ctx, cfg, name)
+				if err != nil {
+					ui.DisplayProviderError(err)
+					failed = err
+					continue
+				}
+				ui.DisplayModels(models, llm.ModelName(cfg, name))
+			}
+			return failed
+		},
+	}
+
+	modelsCmd.Flags().StringVar(&set, "set", "", "Save `model` as the provider's model in the config file")
+	return modelsCmd
+}
+
+// setModel checks that the provider offers model and writes it to the
+// config file.
+func setModel(ctx context.Context, cfg *config.Config, name, model string) error {
+	models, err := llm.ListModels(ctx, cfg, name)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(models, model) && !slices.Contains(models, model+":latest") {
+		return fmt.Errorf("%s does not offer model %s (run how models %s to list them)", name, model, name)
+	}
+
+	// Save the file as written, without API keys from the environment.
+	file, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if err := llm.SetModel(file, name, model); err != nil {
+		return err
+	}
+	if err := config.Save(file); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	fmt.Printf("%s model set to %s\n", name, model)
+	return nil
+}
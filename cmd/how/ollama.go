@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/ui"
+)
+
+// ensureOllamaModel offers to pull the configured model when the native
+// Ollama backend is in use and the model is missing. Failures to reach
+// Ollama are left for the actual request to report.
+func ensureOllamaModel(ctx context.Context, cfg *config.Config) error {
+	if !cfg.Ollama.Native || !usesProvider(cfg, "ollama") {
+		return nil
+	}
+
+	ollama, err := llm.NewOllamaNative(cfg.Ollama)
+	if err != nil {
+		return nil
+	}
+	if ok, err := ollama.HasModel(ctx); err != nil || ok {
+		return nil
+	}
+
+	pull, err := ui.Confirm(fmt.Sprintf("Ollama model %s is not pulled. Pull it now?", ollama.Model()))
+	if err != nil || !pull {
+		return err
+	}
+
+	bar := ui.NewProgressBar(os.Stdout, "pulling "+ollama.Model())
+	err = ollama.Pull(ctx, func(p llm.PullProgress) {
+		bar.Update(p.Status, p.Completed, p.Total)
+	})
+	bar.Finish()
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", ollama.Model(), err)
+	}
+	return nil
+}
+
+// usesProvider reports whether name is the configured provider or part of
+// the fallback chain.
+func usesProvider(cfg *config.Config, name string) bool {
+	if len(cfg.Providers) > 0 {
+		return slices.Contains(cfg.Providers, name)
+	}
+	return cfg.Provider == name
+}
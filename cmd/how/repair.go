@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+	"github.com/swibrow/how/internal/prompt"
+	"github.com/swibrow/how/internal/ui"
+)
+
+// parseResponse returns the result for response. When no command can be
+// parsed, provider is shown its reply with a correction instruction, up to
+// repair_attempts times. The repair turns are not added to req, so a
+// refinement continues from the repaired answer.
+func parseResponse(ctx context.Context, cfg *config.Config, store *memory.Store, provider llm.Provider,
+	req llm.Request, response *llm.Response) (*llm.Response, ui.Result, error) {
+	result := ui.NewResult(response)
+	for attempt := 1; result.Command == "" && attempt <= cfg.RepairAttempts; attempt++ {
+		verbosef("No command in the response, asking again (%d/%d). Response was: %q", attempt, cfg.RepairAttempts, response.Text)
+
+		repair := req
+		repair.Messages = append(slices.Clone(req.Messages),
+			llm.Message{Role: llm.RoleAssistant, Content: response.Text},
+			llm.Message{Role: llm.RoleUser, Content: prompt.FormatRepair()},
+		)
+		var err error
+		response, err = complete(ctx, provider, repair)
+		if err != nil {
+			return nil, result, err
+		}
+		recordUsage(ctx, store, cfg, cfg.Provider, response)
+		result = ui.NewResult(response)
+	}
+	if result.Command == "" {
+		return nil, result, errNoCommand
+	}
+	return response, result, nil
+}
+
+var errNoCommand = errors.New("could not parse a command from the response")
+
+// verbosef logs a diagnostic line to stderr when --verbose is set.
+func verbosef(format string, args ...any) {
+	if flagVerbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
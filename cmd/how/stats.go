@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+)
+
+func newStatsCmd() *cobra.Command {
+	var days int
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show token usage and estimated spend",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+
+			store, err := openMemoryStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close() //nolint:errcheck
+
+			since := time.Now().AddDate(0, 0, -days)
+			rows, err := store.Usage(context.Background(), since)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				fmt.Printf("No usage recorded in the last %d days.\n", days)
+				return nil
+			}
+			races, err := store.RaceStats(context.Background(), since)
+			if err != nil {
+				return err
+			}
+
+			printUsage(cfg, days, rows)
+			printRaces(races)
+			return nil
+		},
+	}
+
+	statsCmd.Flags().IntVar(&days, "days", 30, "Number of days to include")
+	return statsCmd
+}
+
+// usageTotal sums usage for one group. Priced is false when any of the
+// group's models has no configured price, so the cost is a lower bound.
+type usageTotal struct {
+	key          string
+	requests     int64
+	inputTokens  int64
+	outputTokens int64
+	cost         float64
+	priced       bool
+}
+
+func printUsage(cfg *config.Config, days int, rows []memory.UsageRow) {
+	fmt.Printf("Usage over the last %d days\n", days)
+
+	groups := []struct {
+		title  string
+		header string
+		key    func(memory.UsageRow) string
+	}{
+		{"By day", "DAY", func(r memory.UsageRow) string { return r.Day }},
+		{"By provider", "PROVIDER", func(r memory.UsageRow) string { return r.Provider }},
+		{"By model", "MODEL", func(r memory.UsageRow) string { return r.Model }},
+	}
+
+	for _, g := range groups {
+		totals := sumUsage(cfg, rows, g.key)
+		fmt.Printf("\n%s\n", g.title)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "  %s\tREQUESTS\tINPUT\tOUTPUT\tCOST\n", g.header)
+		for _, t := range totals {
+			fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%s\n", t.key, t.requests, t.inputTokens, t.outputTokens, formatCost(t))
+		}
+		w.Flush() //nolint:errcheck
+	}
+
+	total := sumUsage(cfg, rows, func(memory.UsageRow) string { return "" })[0]
+	fmt.Printf("\nTotal: %d requests, %d input and %d output tokens, %s\n",
+		total.requests, total.inputTokens, total.outputTokens, formatCost(total))
+	if !total.priced {
+		fmt.Println("Some models have no price configured; add them under prices in ~/.config/how/config.yaml.")
+	}
+}
+
+// printRaces shows how often each provider won when racing. It prints
+// nothing if no races were run.
+func printRaces(races []memory.RaceRow) {
+	if len(races) == 0 {
+		return
+	}
+	fmt.Printf("\nRace wins\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  PROVIDER\tRACES\tWINS\tWIN RATE\n")
+	for _, r := range races {
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%.0f%%\n", r.Provider, r.Races, r.Wins, 100*float64(r.Wins)/float64(r.Races))
+	}
+	w.Flush() //nolint:errcheck
+}
+
+// sumUsage groups rows by key, keeping the order in which keys first appear.
+func sumUsage(cfg *config.Config, rows []memory.UsageRow, key func(memory.UsageRow) string) []usageTotal {
+	var totals []usageTotal
+	index := map[string]int{}
+	for _, r := range rows {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, usageTotal{key: k, priced: true})
+		}
+		t := &totals[i]
+		t.requests += r.Requests
+		t.inputTokens += r.InputTokens
+		t.outputTokens += r.OutputTokens
+		if price, ok := cfg.Prices[r.Model]; ok {
+			t.cost += price.Cost(r.InputTokens, r.OutputTokens)
+		} else {
+			t.priced = false
+		}
+	}
+	return totals
+}
+
+func formatCost(t usageTotal) string {
+	switch {
+	case t.priced:
+		return fmt.Sprintf("$%.4f", t.cost)
+	case t.cost > 0:
+		return fmt.Sprintf(">= $%.4f", t.cost)
+	default:
+		return "-"
+	}
+}
+
+// recordUsage stores the tokens a provider reported for a response. name
+// is the provider asked; a fallback chain reports the one that answered.
+// Cached responses cost nothing and are skipped.
+func recordUsage(ctx context.Context, store *memory.Store, cfg *config.Config, name string, resp *llm.Response) {
+	if store == nil || resp.Cached {
+		return
+	}
+	provider := resp.Provider
+	if provider == "" {
+		provider = name
+	}
+	_ = store.RecordUsage(ctx, provider, llm.ModelName(cfg, provider), resp.Usage.InputTokens, resp.Usage.OutputTokens)
+}
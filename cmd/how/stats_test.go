@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/memory"
+)
+
+func TestFormatCost(t *testing.T) {
+	cases := []struct {
+		name  string
+		total usageTotal
+		want  string
+	}{
+		{name: "priced", total: usageTotal{cost: 0.0123, priced: true}, want: "$0.0123"},
+		{name: "priced free", total: usageTotal{priced: true}, want: "$0.0000"},
+		{name: "rounds", total: usageTotal{cost: 1.23456, priced: true}, want: "$1.2346"},
+		{name: "partly priced", total: usageTotal{cost: 0.5}, want: ">= $0.5000"},
+		{name: "unpriced", total: usageTotal{}, want: "-"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatCost(tc.total); got != tc.want {
+				t.Errorf("formatCost() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSumUsage(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Prices = map[string]config.ModelPrice{"gpt-4o": {Input: 2.5, Output: 10}}
+	rows := []memory.UsageRow{
+		{Day: "2026-10-01", Provider: "openai", Model: "gpt-4o", Requests: 2, InputTokens: 1_000_000, OutputTokens: 100_000},
+		{Day: "2026-10-01", Provider: "ollama", Model: "llama3", Requests: 1, InputTokens: 500, OutputTokens: 50},
+		{Day: "2026-10-02", Provider: "openai", Model: "gpt-4o", Requests: 1, InputTokens: 0, OutputTokens: 100_000},
+	}
+
+	byDay := sumUsage(cfg, rows, func(r memory.UsageRow) string { return r.Day })
+	if len(byDay) != 2 {
+		t.Fatalf("got %d days, want 2", len(byDay))
+	}
+	if got := formatCost(byDay[0]); got != ">= $3.5000" {
+		t.Errorf("first day: got %s, want >= $3.5000 as llama3 has no price", got)
+	}
+	if got := formatCost(byDay[1]); got != "$1.0000" {
+		t.Errorf("second day: got %s, want $1.0000", got)
+	}
+	if byDay[0].requests != 3 || byDay[0].inputTokens != 1_000_500 {
+		t.Errorf("first day totals: %+v", byDay[0])
+	}
+}
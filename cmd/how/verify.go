@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+	"github.com/swibrow/how/internal/prompt"
+	"github.com/swibrow/how/internal/ui"
+)
+
+// reviewer critiques suggested commands for --verify.
+type reviewer struct {
+	name     string
+	provider llm.Provider
+}
+
+// newReviewer returns the provider named by verify.reviewer, or nil if none
+// is configured and the answering provider reviews its own commands.
+func newReviewer(cfg *config.Config, store *memory.Store) (*reviewer, error) {
+	if cfg.Verify.Reviewer == "" {
+		return nil, nil
+	}
+	rcfg := *cfg
+	rcfg.Provider = cfg.Verify.Reviewer
+	rcfg.Providers = nil
+	p, err := llm.NewProvider(&rcfg)
+	if err != nil {
+		return nil, fmt.Errorf("reviewer %s: %w", cfg.Verify.Reviewer, err)
+	}
+	return &reviewer{name: cfg.Verify.Reviewer, provider: withBudget(store, &rcfg, p)}, nil
+}
+
+// verify has the reviewer (provider itself if rv is nil) critique result
+// under reviewPrompt and, while it fails the review, asks provider for a
+// corrected command up to verify.regenerate times. The rejected turns are
+// added to req so the model sees the critique. It returns the last
+// response and its result with the verdict attached.
+func verify(ctx context.Context, cfg *config.Config, store *memory.Store, rv *reviewer, reviewPrompt string,
+	provider llm.Provider, req *llm.Request, question string, response *llm.Response,
+	result ui.Result) (*llm.Response, ui.Result, error) {
+	if rv == nil {
+		rv = &reviewer{name: cfg.Provider, provider: provider}
+	}
+	for attempt := 1; ; attempt++ {
+		verdict, err := rv.review(ctx, cfg, store, reviewPrompt, question, result)
+		if err != nil {
+			return nil, result, err
+		}
+		result.Verdict = &verdict
+		if verdict.Passed || attempt > cfg.Verify.Regenerate {
+			return response, result, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "Review failed (%s), regenerating (%d/%d)\n", verdict.Critique, attempt, cfg.Verify.Regenerate)
+		req.Messages = append(req.Messages,
+			llm.Message{Role: llm.RoleAssistant, Content: response.Text},
+			llm.Message{Role: llm.RoleUser, Content: prompt.FormatReviewFeedback(verdict.Critique, verdict.Issues)},
+		)
+		response, err = complete(ctx, provider, *req)
+		if err != nil {
+			return nil, result, err
+		}
+		recordUsage(ctx, store, cfg, cfg.Provider, response)
+
+		response, result, err = parseResponse(ctx, cfg, store, provider, *req, response)
+		if err != nil {
+			return nil, result, err
+		}
+	}
+}
+
+// verifyChoice reviews an alternative picked with ui.Pick, which verify
+// did not see. A failing alternative is shown with its verdict rather than
+// regenerated, since the user chose it.
+func verifyChoice(ctx context.Context, cfg *config.Config, store *memory.Store, rv *reviewer, reviewPrompt string,
+	provider llm.Provider, question string, chosen ui.Result) (ui.Result, error) {
+	if chosen.Verdict != nil {
+		return chosen, nil
+	}
+	if rv == nil {
+		rv = &reviewer{name: cfg.Provider, provider: provider}
+	}
+	verdict, err := rv.review(ctx, cfg, store, reviewPrompt, question, chosen)
+	if err != nil {
+		return chosen, err
+	}
+	chosen.Verdict = &verdict
+	return chosen, nil
+}
+
+// review has the reviewer critique result's command.
+func (rv *reviewer) review(ctx context.Context, cfg *config.Config, store *memory.Store, reviewPrompt, question string,
+	result ui.Result) (ui.Verdict, error) {
+	review := llm.NewRequest(reviewPrompt, prompt.FormatReview(question, result.Command, result.Explanation))
+	resp, err := rv.provider.Complete(ctx, review)
+	if err != nil {
+		return ui.Verdict{}, fmt.Errorf("reviewing command: %w", err)
+	}
+	recordUsage(ctx, store, cfg, rv.name, resp)
+	return ui.NewVerdict(resp), nil
+}
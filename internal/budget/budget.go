@@ -0,0 +1,123 @@
+// Package budget enforces the request and spending limits configured under
+// budget, using the usage recorded in the memory database as counters.
+package budget
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/llm"
+	"github.com/swibrow/how/internal/memory"
+)
+
+// ExceededError reports which limit has been reached.
+type ExceededError struct {
+	// Period is "daily" or "monthly".
+	Period string
+	// Requests is true for a request limit, false for a spending limit.
+	Requests bool
+	Limit    float64
+	Used     float64
+}
+
+func (e *ExceededError) Error() string {
+	if e.Requests {
+		return fmt.Sprintf("%s budget of %d requests reached (%d used)", e.Period, int(e.Limit), int(e.Used))
+	}
+	return fmt.Sprintf("%s budget of $%.2f reached ($%.2f spent)", e.Period, e.Limit, e.Used)
+}
+
+// Since returns the start of the period Check needs usage for: the first
+// day of the current month.
+func Since(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// Check returns an *ExceededError if cloud usage in rows has reached one of
+// the configured limits. Rows must cover at least the period from Since.
+// Usage of local providers is free and not counted.
+func Check(cfg *config.Config, rows []memory.UsageRow, now time.Time) error {
+	b := cfg.Budget
+	today := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+
+	var dayRequests, monthRequests int64
+	var dayCost, monthCost float64
+	for _, r := range rows {
+		if llm.IsLocal(cfg, r.Provider) || !strings.HasPrefix(r.Day, month) {
+			continue
+		}
+		cost := cfg.Prices[r.Model].Cost(r.InputTokens, r.OutputTokens)
+		monthRequests += r.Requests
+		monthCost += cost
+		if r.Day == today {
+			dayRequests += r.Requests
+			dayCost += cost
+		}
+	}
+
+	switch {
+	case b.DailyRequests > 0 && dayRequests >= int64(b.DailyRequests):
+		return &ExceededError{Period: "daily", Requests: true, Limit: float64(b.DailyRequests), Used: float64(dayRequests)}
+	case b.MonthlyRequests > 0 && monthRequests >= int64(b.MonthlyRequests):
+		return &ExceededError{Period: "monthly", Requests: true, Limit: float64(b.MonthlyRequests), Used: float64(monthRequests)}
+	case b.DailyCost > 0 && dayCost >= b.DailyCost:
+		return &ExceededError{Period: "daily", Limit: b.DailyCost, Used: dayCost}
+	case b.MonthlyCost > 0 && monthCost >= b.MonthlyCost:
+		return &ExceededError{Period: "monthly", Limit: b.MonthlyCost, Used: monthCost}
+	}
+	return nil
+}
+
+// Applies reports whether the budget covers the configured provider(s):
+// a limit is set and at least one of them is a cloud provider.
+func Applies(cfg *config.Config) bool {
+	if !cfg.Budget.Enabled() {
+		return false
+	}
+	for _, name := range providerNames(cfg) {
+		if !llm.IsLocal(cfg, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unpriced returns the models of the configured cloud provider(s) that
+// have no entry under prices when a cost limit is set. Their spend would
+// count as nothing, so the limit could never be reached.
+func Unpriced(cfg *config.Config) []string {
+	if cfg.Budget.DailyCost <= 0 && cfg.Budget.MonthlyCost <= 0 {
+		return nil
+	}
+	var models []string
+	for _, name := range providerNames(cfg) {
+		if llm.IsLocal(cfg, name) {
+			continue
+		}
+		model := llm.ModelName(cfg, name)
+		if _, ok := cfg.Prices[model]; !ok && !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// UnpricedError reports cloud models a cost limit cannot account for.
+type UnpricedError struct {
+	Models []string
+}
+
+func (e *UnpricedError) Error() string {
+	return fmt.Sprintf("cost limit cannot be enforced: no price for %s under prices", strings.Join(e.Models, ", "))
+}
+
+func providerNames(cfg *config.Config) []string {
+	if len(cfg.Providers) > 0 {
+		return cfg.Providers
+	}
+	return []string{cfg.Provider}
+}
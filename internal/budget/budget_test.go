@@ -0,0 +1,130 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/swibrow/how/internal/config"
+	"github.com/swibrow/how/internal/memory"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+	rows := []memory.UsageRow{
+		{Day: "2026-03-15", Provider: "anthropic", Model: "claude-sonnet-4-6", Requests: 3, InputTokens: 1_000_000, OutputTokens: 100_000},
+		{Day: "2026-03-15", Provider: "ollama", Model: "llama3", Requests: 50, InputTokens: 9_000_000},
+		{Day: "2026-03-02", Provider: "openai", Model: "gpt-4o", Requests: 7, InputTokens: 400_000, OutputTokens: 50_000},
+		{Day: "2026-02-27", Provider: "openai", Model: "gpt-4o", Requests: 100, InputTokens: 9_000_000},
+	}
+
+	cases := []struct {
+		name   string
+		budget config.BudgetConfig
+		want   *ExceededError
+	}{
+		{name: "under all limits", budget: config.BudgetConfig{DailyRequests: 4, MonthlyRequests: 11, DailyCost: 5, MonthlyCost: 10}},
+		{
+			name:   "daily requests ignore local providers",
+			budget: config.BudgetConfig{DailyRequests: 3},
+			want:   &ExceededError{Period: "daily", Requests: true, Limit: 3, Used: 3},
+		},
+		{
+			name:   "monthly requests ignore last month",
+			budget: config.BudgetConfig{MonthlyRequests: 10},
+			want:   &ExceededError{Period: "monthly", Requests: true, Limit: 10, Used: 10},
+		},
+		{
+			name:   "daily cost",
+			budget: config.BudgetConfig{DailyCost: 4},
+			want:   &ExceededError{Period: "daily", Limit: 4, Used: 4.5},
+		},
+		{
+			name:   "monthly cost",
+			budget: config.BudgetConfig{MonthlyCost: 5},
+			want:   &ExceededError{Period: "monthly", Limit: 5, Used: 6},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Budget = tc.budget
+			cfg.Prices = map[string]config.ModelPrice{
+				"claude-sonnet-4-6": {Input: 3, Output: 15},
+				"gpt-4o":            {Input: 2.5, Output: 10},
+			}
+
+			err := Check(cfg, rows, now)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var exceeded *ExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("expected *ExceededError, got %v", err)
+			}
+			if *exceeded != *tc.want {
+				t.Errorf("got %+v, want %+v", *exceeded, *tc.want)
+			}
+		})
+	}
+}
+
+func TestExceededErrorMessage(t *testing.T) {
+	err := &ExceededError{Period: "daily", Requests: true, Limit: 20, Used: 20}
+	if got := err.Error(); got != "daily budget of 20 requests reached (20 used)" {
+		t.Errorf("got %q", got)
+	}
+	err = &ExceededError{Period: "monthly", Limit: 10, Used: 10.456}
+	if got := err.Error(); got != "monthly budget of $10.00 reached ($10.46 spent)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestApplies(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if Applies(cfg) {
+		t.Error("budget without limits should not apply")
+	}
+
+	cfg.Budget.DailyRequests = 10
+	if !Applies(cfg) {
+		t.Error("budget should apply to anthropic")
+	}
+
+	cfg.Provider = "ollama"
+	if Applies(cfg) {
+		t.Error("budget should not apply to a local provider")
+	}
+
+	cfg.Providers = []string{"ollama", "openai"}
+	if !Applies(cfg) {
+		t.Error("budget should apply when the chain includes a cloud provider")
+	}
+}
+
+func TestSince(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 30, 0, 0, time.UTC)
+	if got, want := Since(now), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Since() = %v, want %v", got, want)
+	}
+}
+
+func TestUnpriced(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers = []string{"anthropic", "ollama", "openai"}
+	cfg.Prices = map[string]config.ModelPrice{cfg.Anthropic.Model: {Input: 3, Output: 15}}
+
+	cfg.Budget.DailyRequests = 10
+	if got := Unpriced(cfg); got != nil {
+		t.Errorf("request limits need no prices, got %v", got)
+	}
+
+	cfg.Budget.MonthlyCost = 20
+	if got := Unpriced(cfg); len(got) != 1 || got[0] != cfg.OpenAI.Model {
+		t.Errorf("Unpriced() = %v, want [%s]", got, cfg.OpenAI.Model)
+	}
+}
@@ -0,0 +1,97 @@
+package cloud
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AWSTools are commands that use the active AWS profile.
+var AWSTools = []string{"aws", "sam", "cdk", "eksctl", "terraform", "tofu"}
+
+// AWS is the active AWS profile and region.
+type AWS struct {
+	Profile string
+	Region  string
+}
+
+// Production reports whether the profile name looks like production.
+func (a *AWS) Production() bool {
+	return a != nil && isProduction(a.Profile)
+}
+
+// LoadAWS reads the active profile from AWS_PROFILE (default "default")
+// and its region from AWS_REGION, AWS_DEFAULT_REGION or the profile in
+// ~/.aws/config ($AWS_CONFIG_FILE). It returns nil if AWS is not set up.
+func LoadAWS() *AWS {
+	a := &AWS{Profile: os.Getenv("AWS_PROFILE")}
+	if a.Profile == "" {
+		a.Profile = os.Getenv("AWS_DEFAULT_PROFILE")
+	}
+	explicit := a.Profile != ""
+	if !explicit {
+		a.Profile = "default"
+	}
+
+	a.Region = os.Getenv("AWS_REGION")
+	if a.Region == "" {
+		a.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	found := false
+	if path := awsConfigPath(); path != "" {
+		region, ok := profileRegion(path, a.Profile)
+		found = ok
+		if a.Region == "" {
+			a.Region = region
+		}
+	}
+	if !explicit && !found && a.Region == "" {
+		return nil
+	}
+	return a
+}
+
+func awsConfigPath() string {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", "config")
+}
+
+// profileRegion returns the region set for profile in an AWS config file,
+// and whether the profile exists there.
+func profileRegion(path, profile string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close() //nolint:errcheck
+
+	section := "profile " + profile
+	if profile == "default" {
+		section = "default"
+	}
+
+	found, in := false, false
+	region := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			in = strings.TrimSpace(strings.Trim(line, "[]")) == section
+			found = found || in
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if in && ok && strings.TrimSpace(key) == "region" {
+			region = strings.TrimSpace(value)
+		}
+	}
+	return region, found
+}
@@ -0,0 +1,64 @@
+package cloud
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const awsConfig = `[default]
+region = us-east-1
+
+[profile prod-admin]
+region = eu-west-1
+`
+
+func setAWSEnv(t *testing.T, profile, region string) {
+	t.Helper()
+	t.Setenv("AWS_PROFILE", profile)
+	t.Setenv("AWS_DEFAULT_PROFILE", "")
+	t.Setenv("AWS_REGION", region)
+	t.Setenv("AWS_DEFAULT_REGION", "")
+}
+
+func TestLoadAWS(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", writeFile(t, "config", awsConfig))
+
+	tests := []struct {
+		name, profile, region string
+		want                  AWS
+	}{
+		{"default profile", "", "", AWS{Profile: "default", Region: "us-east-1"}},
+		{"named profile", "prod-admin", "", AWS{Profile: "prod-admin", Region: "eu-west-1"}},
+		{"region from env", "prod-admin", "ap-south-1", AWS{Profile: "prod-admin", Region: "ap-south-1"}},
+		{"unknown profile", "sandbox", "", AWS{Profile: "sandbox"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setAWSEnv(t, tc.profile, tc.region)
+			a := LoadAWS()
+			if a == nil || *a != tc.want {
+				t.Errorf("got %+v, want %+v", a, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAWSNotConfigured(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "missing"))
+	setAWSEnv(t, "", "")
+	if a := LoadAWS(); a != nil {
+		t.Errorf("expected nil, got %+v", a)
+	}
+}
+
+func TestAWSProduction(t *testing.T) {
+	if !(&AWS{Profile: "prod-admin"}).Production() {
+		t.Error("expected prod-admin to look like production")
+	}
+	if (&AWS{Profile: "default"}).Production() {
+		t.Error("expected default not to look like production")
+	}
+	if (*AWS)(nil).Production() {
+		t.Error("expected nil not to look like production")
+	}
+}
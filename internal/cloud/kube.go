@@ -0,0 +1,109 @@
+// Package cloud reads which Kubernetes context and AWS profile commands
+// will act on, so suggestions target the right place and production is
+// called out.
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubeTools are commands that act on the current kubectl context.
+var KubeTools = []string{"kubectl", "helm", "k9s", "kubectx", "kubens", "kustomize", "stern", "flux", "argocd"}
+
+// Kube is the active kubectl context.
+type Kube struct {
+	Context   string
+	Cluster   string
+	Namespace string
+}
+
+// Production reports whether the context, cluster or namespace name looks
+// like production.
+func (k *Kube) Production() bool {
+	return k != nil && isProduction(k.Context, k.Cluster, k.Namespace)
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// LoadKube reads the current context from $KUBECONFIG, or ~/.kube/config
+// if it is not set. As with kubectl, the first file that sets a value wins.
+// It returns nil if no current context is set.
+func LoadKube() *Kube {
+	return LoadKubeContext(os.Getenv("KUBECONFIG"), "")
+}
+
+// LoadKubeContext reads the named context, or the current one if name is
+// "", from files, a path list like $KUBECONFIG that defaults to
+// ~/.kube/config when empty. It is used for commands that pick their own
+// context with --context or KUBECONFIG=.
+func LoadKubeContext(files, name string) *Kube {
+	paths := filepath.SplitList(files)
+	if len(paths) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		paths = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	var configs []kubeconfig
+	current := name
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var kc kubeconfig
+		if yaml.Unmarshal(data, &kc) != nil {
+			continue
+		}
+		if current == "" {
+			current = kc.CurrentContext
+		}
+		configs = append(configs, kc)
+	}
+	if current == "" {
+		return nil
+	}
+
+	k := &Kube{Context: current, Namespace: "default"}
+	for _, kc := range configs {
+		for _, c := range kc.Contexts {
+			if c.Name != current {
+				continue
+			}
+			k.Cluster = c.Context.Cluster
+			if c.Context.Namespace != "" {
+				k.Namespace = c.Context.Namespace
+			}
+			return k
+		}
+	}
+	return k
+}
+
+// productionRe matches names such as prod, production, prd or live as a
+// whole word, e.g. "eks-prod-eu" but not "product-catalog".
+var productionRe = regexp.MustCompile(`(?i)(^|[^a-z])(prod|production|prd|live)([^a-z]|$)`)
+
+func isProduction(names ...string) bool {
+	for _, name := range names {
+		if productionRe.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKube(t *testing.T) {
+	first := writeFile(t, "first", "current-context: eks-prod\n")
+	second := writeFile(t, "second", `current-context: kind-dev
+contexts:
+- name: kind-dev
+  context:
+    cluster: kind-dev
+- name: eks-prod
+  context:
+    cluster: arn:aws:eks:eu-west-1:123:cluster/prod
+    namespace: payments
+`)
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+
+	k := LoadKube()
+	if k == nil {
+		t.Fatal("expected a context")
+	}
+	want := Kube{Context: "eks-prod", Cluster: "arn:aws:eks:eu-west-1:123:cluster/prod", Namespace: "payments"}
+	if *k != want {
+		t.Errorf("got %+v, want %+v", *k, want)
+	}
+	if !k.Production() {
+		t.Error("expected eks-prod to look like production")
+	}
+}
+
+func TestLoadKubeDefaultNamespace(t *testing.T) {
+	t.Setenv("KUBECONFIG", writeFile(t, "config", "current-context: minikube\ncontexts:\n- name: minikube\n  context:\n    cluster: minikube\n"))
+
+	k := LoadKube()
+	if k == nil || k.Namespace != "default" || k.Production() {
+		t.Errorf("got %+v", k)
+	}
+}
+
+func TestLoadKubeNoContext(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	if k := LoadKube(); k != nil {
+		t.Errorf("expected nil, got %+v", k)
+	}
+}
+
+func TestIsProduction(t *testing.T) {
+	tests := map[string]bool{
+		"prod":            true,
+		"eks-prod-eu":     true,
+		"Production":      true,
+		"gke_acme_prd":    true,
+		"live":            true,
+		"product-catalog": false,
+		"staging":         false,
+		"deliverables":    false,
+		"":                false,
+	}
+	for name, want := range tests {
+		if got := isProduction(name); got != want {
+			t.Errorf("isProduction(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLoadKubeContext(t *testing.T) {
+	path := writeFile(t, "config", `current-context: kind-dev
+contexts:
+- name: kind-dev
+  context:
+    cluster: kind-dev
+- name: eks-prod
+  context:
+    cluster: prod
+    namespace: payments
+`)
+
+	k := LoadKubeContext(path, "eks-prod")
+	if want := (Kube{Context: "eks-prod", Cluster: "prod", Namespace: "payments"}); k == nil || *k != want {
+		t.Errorf("got %+v, want %+v", k, want)
+	}
+	if k := LoadKubeContext(path, ""); k == nil || k.Context != "kind-dev" {
+		t.Errorf("expected the current context, got %+v", k)
+	}
+	if k := LoadKubeContext(path, "gke-live"); k == nil || !k.Production() {
+		t.Errorf("expected an unknown context to be judged by its name, got %+v", k)
+	}
+}
@@ -4,41 +4,177 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Provider     string          `yaml:"provider"`
-	SystemPrompt string          `yaml:"system_prompt,omitempty"`
-	Anthropic    AnthropicConfig `yaml:"anthropic"`
-	OpenAI       OpenAIConfig    `yaml:"openai"`
-	Ollama       OllamaConfig    `yaml:"ollama"`
-	Memory       MemoryConfig    `yaml:"memory"`
+	Provider       string                            `yaml:"provider"`
+	Providers      []string                          `yaml:"providers,omitempty"`
+	Strategy       string                            `yaml:"strategy,omitempty"`
+	SystemPrompt   string                            `yaml:"system_prompt,omitempty"`
+	Alternatives   int                               `yaml:"alternatives,omitempty"`
+	Timeout        time.Duration                     `yaml:"timeout"`
+	MaxRetries     int                               `yaml:"max_retries"`
+	RepairAttempts int                               `yaml:"repair_attempts"`
+	Anthropic      AnthropicConfig                   `yaml:"anthropic"`
+	OpenAI         OpenAIConfig                      `yaml:"openai"`
+	Ollama         OllamaConfig                      `yaml:"ollama"`
+	Azure          AzureConfig                       `yaml:"azure,omitempty"`
+	Gemini         GeminiConfig                      `yaml:"gemini"`
+	LlamaCpp       LlamaCppConfig                    `yaml:"llamacpp"`
+	Replay         ReplayConfig                      `yaml:"replay,omitempty"`
+	Compatible     map[string]OpenAICompatibleConfig `yaml:"openai_compatible,omitempty"`
+	Memory         MemoryConfig                      `yaml:"memory"`
+	Cache          CacheConfig                       `yaml:"cache"`
+	Prices         map[string]ModelPrice             `yaml:"prices,omitempty"`
+	Budget         BudgetConfig                      `yaml:"budget,omitempty"`
+	Verify         VerifyConfig                      `yaml:"verify"`
+	Environment    EnvironmentConfig                 `yaml:"environment"`
+}
+
+// EnvironmentConfig controls probing the machine for installed tools,
+// which are listed in the system prompt.
+type EnvironmentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL is how long probe results are cached in the config directory.
+	TTL time.Duration `yaml:"ttl"`
+	// Tools are probed in addition to the built-in list.
+	Tools []string `yaml:"tools,omitempty"`
 }
 
 type MemoryConfig struct {
 	Enabled bool `yaml:"enabled"`
 }
 
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+// ModelPrice is what a model costs in US dollars per million tokens. The
+// map key under prices is the model name.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// Cost returns the price of the given token counts in US dollars.
+func (p ModelPrice) Cost(inputTokens, outputTokens int64) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
+
+// VerifyConfig controls the review pass that critiques each suggested
+// command. Reviewer names the provider to ask; empty means the one that
+// answered. A rejected command is regenerated up to Regenerate times.
+type VerifyConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Reviewer   string `yaml:"reviewer,omitempty"`
+	Regenerate int    `yaml:"regenerate"`
+}
+
+// BudgetConfig limits requests to cloud providers. Zero means no limit.
+// Costs are estimated from prices. When a limit is reached, requests go to
+// the Fallback provider if one is set and are refused otherwise.
+type BudgetConfig struct {
+	DailyRequests   int     `yaml:"daily_requests,omitempty"`
+	MonthlyRequests int     `yaml:"monthly_requests,omitempty"`
+	DailyCost       float64 `yaml:"daily_cost,omitempty"`
+	MonthlyCost     float64 `yaml:"monthly_cost,omitempty"`
+	Fallback        string  `yaml:"fallback,omitempty"`
+}
+
+// Enabled reports whether any limit is set.
+func (b BudgetConfig) Enabled() bool {
+	return b.DailyRequests > 0 || b.MonthlyRequests > 0 || b.DailyCost > 0 || b.MonthlyCost > 0
+}
+
 type AnthropicConfig struct {
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	APIKey  string        `yaml:"api_key"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 type OpenAIConfig struct {
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	APIKey  string        `yaml:"api_key"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 type OllamaConfig struct {
-	Model string `yaml:"model"`
-	URL   string `yaml:"url"`
+	Model     string        `yaml:"model"`
+	URL       string        `yaml:"url"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+	Native    bool          `yaml:"native,omitempty"`
+	KeepAlive string        `yaml:"keep_alive,omitempty"`
+	Options   OllamaOptions `yaml:"options,omitempty"`
+}
+
+// OllamaOptions are model parameters passed through the native API.
+type OllamaOptions struct {
+	NumCtx      int      `yaml:"num_ctx,omitempty"`
+	Temperature *float64 `yaml:"temperature,omitempty"`
+	Seed        *int     `yaml:"seed,omitempty"`
+}
+
+type GeminiConfig struct {
+	APIKey  string        `yaml:"api_key"`
+	Model   string        `yaml:"model"`
+	BaseURL string        `yaml:"base_url,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// LlamaCppConfig configures a llama.cpp llama-server. The server runs a
+// single model; Model only labels it for the response cache.
+type LlamaCppConfig struct {
+	URL      string        `yaml:"url"`
+	Model    string        `yaml:"model,omitempty"`
+	NPredict int           `yaml:"n_predict,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ReplayConfig points the replay provider at a cassette recorded with
+// --record.
+type ReplayConfig struct {
+	Cassette string `yaml:"cassette"`
+}
+
+type AzureConfig struct {
+	Endpoint     string        `yaml:"endpoint"`
+	Deployment   string        `yaml:"deployment"`
+	APIVersion   string        `yaml:"api_version"`
+	APIKey       string        `yaml:"api_key,omitempty"`
+	TokenEnv     string        `yaml:"token_env,omitempty"`
+	TokenCommand string        `yaml:"token_command,omitempty"`
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	// NoStreamUsage leaves out stream_options, for API versions that
+	// reject it. Token usage of streamed answers is then not recorded.
+	NoStreamUsage bool `yaml:"no_stream_usage,omitempty"`
+}
+
+// OpenAICompatibleConfig configures a named server that speaks the OpenAI
+// chat completions API. The map key under openai_compatible is the name
+// used in provider/providers.
+type OpenAICompatibleConfig struct {
+	BaseURL   string            `yaml:"base_url"`
+	APIKey    string            `yaml:"api_key,omitempty"`
+	APIKeyEnv string            `yaml:"api_key_env,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Model     string            `yaml:"model"`
+	Timeout   time.Duration     `yaml:"timeout,omitempty"`
+	PlainText bool              `yaml:"plain_text,omitempty"`
+	// NoStreamUsage leaves out stream_options, for servers that reject
+	// it. Token usage of streamed answers is then not recorded.
+	NoStreamUsage bool `yaml:"no_stream_usage,omitempty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Provider: "anthropic",
+		Provider:       "anthropic",
+		Timeout:        60 * time.Second,
+		MaxRetries:     2,
+		RepairAttempts: 2,
 		Anthropic: AnthropicConfig{
 			Model: "claude-sonnet-4-6",
 		},
@@ -49,9 +185,30 @@ func DefaultConfig() *Config {
 			Model: "llama3",
 			URL:   "http://localhost:11434/v1",
 		},
+		Azure: AzureConfig{
+			APIVersion: "2024-10-21",
+		},
+		Gemini: GeminiConfig{
+			Model: "gemini-2.5-flash",
+		},
+		LlamaCpp: LlamaCppConfig{
+			URL:      "http://localhost:8080",
+			NPredict: 256,
+		},
 		Memory: MemoryConfig{
 			Enabled: true,
 		},
+		Cache: CacheConfig{
+			Enabled: true,
+			TTL:     7 * 24 * time.Hour,
+		},
+		Verify: VerifyConfig{
+			Regenerate: 2,
+		},
+		Environment: EnvironmentConfig{
+			Enabled: true,
+			TTL:     24 * time.Hour,
+		},
 	}
 }
 
@@ -79,7 +236,35 @@ func configPath() (string, error) {
 	return filepath.Join(dir, "config.yaml"), nil
 }
 
+// Load reads the config file, applying defaults for missing fields and
+// API keys from the environment.
 func Load() (*Config, error) {
+	cfg, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Env vars take precedence over config file
+	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
+		cfg.Anthropic.APIKey = key
+	}
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		cfg.OpenAI.APIKey = key
+	}
+	if key := os.Getenv("AZURE_OPENAI_API_KEY"); key != "" {
+		cfg.Azure.APIKey = key
+	}
+	if key := os.Getenv("GEMINI_API_KEY"); key != "" {
+		cfg.Gemini.APIKey = key
+	}
+
+	return cfg, nil
+}
+
+// LoadFile reads the config file without environment overrides. Use it to
+// modify and Save the config, so keys from the environment are not written
+// to disk.
+func LoadFile() (*Config, error) {
 	cfg := DefaultConfig()
 
 	path, err := configPath()
@@ -98,15 +283,6 @@ func Load() (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
-
-	// Env vars take precedence over config file
-	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
-		cfg.Anthropic.APIKey = key
-	}
-	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		cfg.OpenAI.APIKey = key
-	}
-
 	return cfg, nil
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func setupTestDir(t *testing.T) {
@@ -30,6 +31,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Ollama.URL != "http://localhost:11434/v1" {
 		t.Errorf("unexpected ollama URL: %q", cfg.Ollama.URL)
 	}
+	if !cfg.Environment.Enabled || cfg.Environment.TTL != 24*time.Hour {
+		t.Errorf("unexpected environment config: %+v", cfg.Environment)
+	}
 }
 
 func TestLoadNoFile(t *testing.T) {
@@ -51,6 +55,7 @@ func TestSaveAndLoad(t *testing.T) {
 	original.Provider = "openai"
 	original.OpenAI.APIKey = "test-key-123"
 	original.OpenAI.Model = "gpt-4o-mini"
+	original.Ollama.Timeout = 3 * time.Minute
 
 	if err := Save(original); err != nil {
 		t.Fatalf("Save() error: %v", err)
@@ -70,6 +75,12 @@ func TestSaveAndLoad(t *testing.T) {
 	if loaded.OpenAI.Model != original.OpenAI.Model {
 		t.Errorf("openai model: got %q, want %q", loaded.OpenAI.Model, original.OpenAI.Model)
 	}
+	if loaded.Timeout != original.Timeout {
+		t.Errorf("timeout: got %v, want %v", loaded.Timeout, original.Timeout)
+	}
+	if loaded.Ollama.Timeout != original.Ollama.Timeout {
+		t.Errorf("ollama timeout: got %v, want %v", loaded.Ollama.Timeout, original.Ollama.Timeout)
+	}
 }
 
 func TestEnvVarOverride(t *testing.T) {
@@ -83,6 +94,7 @@ func TestEnvVarOverride(t *testing.T) {
 
 	t.Setenv("ANTHROPIC_API_KEY", "env-anthropic-key")
 	t.Setenv("OPENAI_API_KEY", "env-openai-key")
+	t.Setenv("GEMINI_API_KEY", "env-gemini-key")
 
 	loaded, err := Load()
 	if err != nil {
@@ -95,6 +107,38 @@ func TestEnvVarOverride(t *testing.T) {
 	if loaded.OpenAI.APIKey != "env-openai-key" {
 		t.Errorf("openai key: got %q, want %q", loaded.OpenAI.APIKey, "env-openai-key")
 	}
+	if loaded.Gemini.APIKey != "env-gemini-key" {
+		t.Errorf("gemini key: got %q, want %q", loaded.Gemini.APIKey, "env-gemini-key")
+	}
+}
+
+func TestLoadFileIgnoresEnv(t *testing.T) {
+	setupTestDir(t)
+
+	cfg := DefaultConfig()
+	cfg.Anthropic.Model = "claude-haiku-4-5"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "env-anthropic-key")
+
+	loaded, err := LoadFile()
+	if err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	if loaded.Anthropic.APIKey != "" {
+		t.Errorf("anthropic key: got %q, want it left empty", loaded.Anthropic.APIKey)
+	}
+	if loaded.Anthropic.Model != "claude-haiku-4-5" {
+		t.Errorf("anthropic model: got %q", loaded.Anthropic.Model)
+	}
+}
+
+func TestModelPriceCost(t *testing.T) {
+	price := ModelPrice{Input: 3, Output: 15}
+	if got := price.Cost(2_000_000, 100_000); got != 7.5 {
+		t.Errorf("Cost() = %v, want 7.5", got)
+	}
 }
 
 func TestShowNoFile(t *testing.T) {
@@ -146,5 +190,7 @@ func TestMain(m *testing.M) {
 	// Ensure tests don't accidentally use real env vars
 	os.Unsetenv("ANTHROPIC_API_KEY")
 	os.Unsetenv("OPENAI_API_KEY")
+	os.Unsetenv("AZURE_OPENAI_API_KEY")
+	os.Unsetenv("GEMINI_API_KEY")
 	os.Exit(m.Run())
 }
@@ -0,0 +1,164 @@
+// Package env probes the user's machine for the tools, shell, distribution
+// and package manager available, so suggestions can be limited to what is
+// actually installed.
+package env
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Tools is the curated list of commands probed on PATH: common tools the
+// model likes to suggest that are often missing, and the usual alternatives
+// to them.
+var Tools = []string{
+	"7z", "aws", "az", "bat", "curl", "delta", "dig", "docker", "duf", "dust",
+	"eza", "fd", "ffmpeg", "fzf", "gawk", "gcloud", "gh", "git", "gsed", "helm",
+	"htop", "http", "ip", "jq", "kubectl", "lsof", "magick", "nc", "netstat",
+	"nmap", "node", "parallel", "pbcopy", "podman", "python3", "rg", "rsync",
+	"sd", "ss", "terraform", "tmux", "tree", "unzip", "watch", "wget", "xclip",
+	"xdg-open", "yq", "zip",
+}
+
+// packageManagers is checked in order; system package managers come before
+// brew and nix, which are often installed alongside them.
+var packageManagers = []string{
+	"apt", "dnf", "yum", "pacman", "zypper", "apk", "emerge", "xbps-install",
+	"brew", "port", "nix-env", "winget", "choco", "scoop",
+}
+
+// Environment describes the user's machine.
+type Environment struct {
+	OS             string `json:"os"`
+	Distro         string `json:"distro,omitempty"`
+	Shell          string `json:"shell,omitempty"`
+	PackageManager string `json:"package_manager,omitempty"`
+	// Installed and Missing split the probed tools by whether they were
+	// found on PATH.
+	Installed []string  `json:"installed"`
+	Missing   []string  `json:"missing"`
+	ProbedAt  time.Time `json:"probed_at"`
+}
+
+// Probe inspects the machine, checking each of tools on PATH.
+func Probe(tools []string) Environment {
+	e := Environment{
+		OS:       runtime.GOOS,
+		Distro:   distro(),
+		Shell:    loginShell(),
+		ProbedAt: time.Now(),
+	}
+	for _, pm := range packageManagers {
+		if _, err := exec.LookPath(pm); err == nil {
+			e.PackageManager = pm
+			break
+		}
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err == nil {
+			e.Installed = append(e.Installed, tool)
+		} else {
+			e.Missing = append(e.Missing, tool)
+		}
+	}
+	return e
+}
+
+// Load returns the environment cached in dir if it is younger than ttl and
+// probed the same tools, and otherwise probes again and updates the cache.
+// Failing to read or write the cache only costs a fresh probe.
+func Load(dir string, ttl time.Duration, tools []string) Environment {
+	path := filepath.Join(dir, "environment.json")
+	if data, err := os.ReadFile(path); err == nil {
+		var cached Environment
+		if json.Unmarshal(data, &cached) == nil && time.Since(cached.ProbedAt) < ttl && cached.probed(tools) {
+			return cached
+		}
+	}
+
+	e := Probe(tools)
+	if data, err := json.Marshal(e); err == nil {
+		_ = os.WriteFile(path, data, 0o644)
+	}
+	return e
+}
+
+// probed reports whether e checked exactly the given tools.
+func (e Environment) probed(tools []string) bool {
+	got := append(slices.Clone(e.Installed), e.Missing...)
+	want := slices.Clone(tools)
+	slices.Sort(got)
+	slices.Sort(want)
+	return slices.Equal(got, want)
+}
+
+// distro returns the distribution name from /etc/os-release on Linux, or
+// the macOS version.
+func distro() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		defer f.Close() //nolint:errcheck
+		return parseOSRelease(f)
+	case "darwin":
+		out, err := exec.Command("sw_vers", "-productVersion").Output()
+		if err != nil {
+			return "macOS"
+		}
+		return "macOS " + strings.TrimSpace(string(out))
+	default:
+		return ""
+	}
+}
+
+// parseOSRelease returns PRETTY_NAME from an os-release file, falling back
+// to NAME and VERSION_ID.
+func parseOSRelease(r io.Reader) string {
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+	if name := fields["PRETTY_NAME"]; name != "" {
+		return name
+	}
+	return strings.TrimSpace(fields["NAME"] + " " + fields["VERSION_ID"])
+}
+
+// loginShell returns the name of the user's login shell from $SHELL or,
+// failing that, /etc/passwd.
+func loginShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile("/etc/passwd")
+	if err != nil {
+		return ""
+	}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) == 7 && fields[0] == u.Username {
+			return filepath.Base(fields[6])
+		}
+	}
+	return ""
+}
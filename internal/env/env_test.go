@@ -0,0 +1,79 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePath puts executables with the given names in a temp dir and makes
+// it the only entry on PATH.
+func fakePath(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestProbe(t *testing.T) {
+	fakePath(t, "rg", "apt")
+	t.Setenv("SHELL", "/usr/bin/zsh")
+
+	e := Probe([]string{"rg", "fd"})
+	if !slices.Equal(e.Installed, []string{"rg"}) || !slices.Equal(e.Missing, []string{"fd"}) {
+		t.Errorf("installed %v, missing %v", e.Installed, e.Missing)
+	}
+	if e.Shell != "zsh" {
+		t.Errorf("shell: got %q, want zsh", e.Shell)
+	}
+	if e.PackageManager != "apt" {
+		t.Errorf("package manager: got %q, want apt", e.PackageManager)
+	}
+}
+
+func TestLoadCaches(t *testing.T) {
+	dir := t.TempDir()
+	fakePath(t, "rg")
+	tools := []string{"rg", "fd"}
+
+	first := Load(dir, time.Hour, tools)
+	fakePath(t, "rg", "fd")
+
+	// Within the TTL the cached probe is used even though fd now exists.
+	if got := Load(dir, time.Hour, tools); !slices.Equal(got.Missing, []string{"fd"}) || !got.ProbedAt.Equal(first.ProbedAt) {
+		t.Errorf("expected the cached probe, got %+v", got)
+	}
+	// An expired cache is probed again.
+	if got := Load(dir, 0, tools); len(got.Missing) != 0 {
+		t.Errorf("expected a fresh probe, got missing %v", got.Missing)
+	}
+	// So is a cache that checked different tools.
+	fakePath(t)
+	if got := Load(dir, time.Hour, []string{"rg", "fd", "jq"}); len(got.Missing) != 3 {
+		t.Errorf("expected a fresh probe for a new tool list, got %+v", got)
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	cases := []struct {
+		name, input, want string
+	}{
+		{"pretty name", "NAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\n", "Ubuntu 24.04.1 LTS"},
+		{"name and version", "NAME=Fedora\nVERSION_ID=40\n", "Fedora 40"},
+		{"empty", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseOSRelease(strings.NewReader(tc.input)); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package input reads data piped into how, such as a log or a JSON sample,
+// and cuts it down to an excerpt that is safe to send with the question.
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxRead caps how much piped input is read at all.
+	maxRead = 1 << 20
+	// maxExcerpt caps the excerpt sent to the model. Longer input keeps its
+	// head and, as the end of a log is usually what matters, a larger tail.
+	maxExcerpt = 8 << 10
+	headBytes  = 2 << 10
+)
+
+// Excerpt is the part of the piped input sent to the model.
+type Excerpt struct {
+	Text string
+	// Size is the number of bytes read, up to maxRead.
+	Size int
+	// Omitted counts the bytes left out of the middle of Text.
+	Omitted int
+	// Partial is set when the input was longer than maxRead.
+	Partial bool
+	// Redacted counts the secrets replaced in Text.
+	Redacted int
+	// Binary is set when the input is not text, in which case Text is
+	// empty.
+	Binary bool
+}
+
+// Piped reports whether f is a pipe or a redirected file rather than a
+// terminal or a device such as /dev/null.
+func Piped(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || mode.IsRegular()
+}
+
+// Read reads r and returns an excerpt of it with secrets redacted. It
+// returns nil if the input is empty.
+func Read(r io.Reader) (*Excerpt, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxRead+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading stdin: %w", err)
+	}
+	e := &Excerpt{Size: len(data)}
+	if len(data) > maxRead {
+		data = data[:maxRead]
+		e.Size = maxRead
+		e.Partial = true
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(trimRune(data)) {
+		e.Binary = true
+		return e, nil
+	}
+
+	// Only the excerpt is redacted, which keeps large input fast.
+	head, tail, omitted := cut(string(data))
+	e.Text, e.Redacted = Redact(head)
+	if omitted > 0 {
+		tail, n := Redact(tail)
+		e.Text += fmt.Sprintf("[... %d bytes omitted ...]\n%s", omitted, tail)
+		e.Redacted += n
+		e.Omitted = omitted
+	}
+	return e, nil
+}
+
+// trimRune drops a multi-byte character cut off by maxRead.
+func trimRune(data []byte) []byte {
+	for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+		if r, _ := utf8.DecodeLastRune(data); r != utf8.RuneError {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// cut splits text longer than maxExcerpt into a head and tail, cutting at
+// line breaks, and returns the number of bytes left out between them.
+func cut(text string) (head, tail string, omitted int) {
+	if len(text) <= maxExcerpt {
+		return text, "", 0
+	}
+	head = text[:headBytes]
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i+1]
+	}
+	tail = text[len(text)-(maxExcerpt-headBytes):]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	// Without line breaks the cuts can split a character.
+	head, tail = strings.ToValidUTF8(head, ""), strings.ToValidUTF8(tail, "")
+	return head, tail, len(text) - len(head) - len(tail)
+}
@@ -20,7 +20,11 @@ func NewAnthropic(cfg config.AnthropicConfig) (*Anthropic, error) {
 		return nil, fmt.Errorf("anthropic API key not set (set ANTHROPIC_API_KEY or configure in ~/.config/how/config.yaml)")
 	}
 
-	client := anthropic.NewClient(option.WithAPIKey(cfg.APIKey))
+	// Retries are handled by Retrying so every backend behaves the same.
+	client := anthropic.NewClient(
+		option.WithAPIKey(cfg.APIKey),
+		option.WithMaxRetries(0),
+	)
 
 	return &Anthropic{
 		client: &client,
@@ -28,27 +32,107 @@ func NewAnthropic(cfg config.AnthropicConfig) (*Anthropic, error) {
 	}, nil
 }
 
-func (a *Anthropic) Complete(ctx context.Context, systemPrompt, userQuery string) (string, error) {
-	resp, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
+// params builds the request. The model is forced to answer through the
+// suggest_command tool so the result arrives as schema-shaped JSON.
+func (a *Anthropic) params(req Request) anthropic.MessageNewParams {
+	messages := make([]anthropic.MessageParam, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		block := anthropic.NewTextBlock(m.Content)
+		if m.Role == RoleAssistant {
+			messages = append(messages, anthropic.NewAssistantMessage(block))
+		} else {
+			messages = append(messages, anthropic.NewUserMessage(block))
+		}
+	}
+
+	return anthropic.MessageNewParams{
 		Model:     anthropic.Model(a.model),
 		MaxTokens: 1024,
 		System: []anthropic.TextBlockParam{
-			{Text: systemPrompt},
+			{Text: req.SystemPrompt()},
 		},
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userQuery)),
+		Messages: messages,
+		Tools: []anthropic.ToolUnionParam{
+			{OfTool: &anthropic.ToolParam{
+				Name:        suggestToolName,
+				Description: anthropic.String(suggestToolDescription),
+				InputSchema: anthropic.ToolInputSchemaParam{
+					Properties: responseProperties,
+					Required:   responseRequired,
+				},
+			}},
 		},
-	})
+		ToolChoice: anthropic.ToolChoiceParamOfTool(suggestToolName),
+	}
+}
+
+func (a *Anthropic) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := a.client.Messages.New(ctx, a.params(req))
 	if err != nil {
-		return "", fmt.Errorf("anthropic API error: %w", err)
+		return nil, wrapError("anthropic", err)
 	}
 
+	usage := Usage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens}
+
 	var parts []string
 	for _, block := range resp.Content {
-		if block.Type == "text" {
+		switch block.Type {
+		case "tool_use":
+			if block.Name == suggestToolName {
+				return withUsage(decodeResponse(string(block.Input)), usage), nil
+			}
+		case "text":
 			parts = append(parts, block.Text)
 		}
 	}
 
-	return strings.Join(parts, ""), nil
+	return withUsage(decodeResponse(strings.Join(parts, "")), usage), nil
+}
+
+func (a *Anthropic) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	stream := a.client.Messages.NewStreaming(ctx, a.params(req))
+	defer stream.Close() //nolint:errcheck
+
+	var text, input strings.Builder
+	var usage Usage
+	for stream.Next() {
+		event := stream.Current()
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			// Counts on message_delta are cumulative.
+			usage.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "input_json_delta":
+				input.WriteString(event.Delta.PartialJSON)
+				onChunk(event.Delta.PartialJSON)
+			case "text_delta":
+				text.WriteString(event.Delta.Text)
+				onChunk(event.Delta.Text)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, wrapError("anthropic", err)
+	}
+
+	if input.Len() > 0 {
+		return withUsage(decodeResponse(input.String()), usage), nil
+	}
+	return withUsage(decodeResponse(text.String()), usage), nil
+}
+
+// Models implements ModelLister using the models API.
+func (a *Anthropic) Models(ctx context.Context) ([]string, error) {
+	pager := a.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	var models []string
+	for pager.Next() {
+		models = append(models, pager.Current().ID)
+	}
+	if err := pager.Err(); err != nil {
+		return nil, wrapError("anthropic", err)
+	}
+	return models, nil
 }
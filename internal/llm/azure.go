@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/option"
+	"github.com/swibrow/how/internal/config"
+)
+
+// tokenCommandTimeout bounds how long token_command may take, e.g. an
+// `az account get-access-token` call.
+const tokenCommandTimeout = 30 * time.Second
+
+// NewAzure creates a client for an Azure OpenAI deployment. Requests go to
+// {endpoint}/openai/deployments/{deployment} with the api-version query
+// parameter, authenticated with an api-key header or, when token_env or
+// token_command is set, an Entra ID bearer token.
+func NewAzure(cfg config.AzureConfig) (*OpenAICompatible, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("azure endpoint not set (configure azure.endpoint in ~/.config/how/config.yaml)")
+	}
+	if cfg.Deployment == "" {
+		return nil, fmt.Errorf("azure deployment not set (configure azure.deployment in ~/.config/how/config.yaml)")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.Endpoint, "/") + "/openai/deployments/" + cfg.Deployment + "/"
+	opts := []option.RequestOption{
+		option.WithBaseURL(baseURL),
+		option.WithQuery("api-version", cfg.APIVersion),
+	}
+
+	var credential string
+	switch {
+	case cfg.TokenEnv != "" || cfg.TokenCommand != "":
+		if cfg.TokenCommand == "" && os.Getenv(cfg.TokenEnv) == "" {
+			return nil, fmt.Errorf("azure token_env %s is empty", cfg.TokenEnv)
+		}
+		opts = append(opts, withAzureToken(cfg))
+		credential = "azure.token_command"
+		if cfg.TokenEnv != "" && os.Getenv(cfg.TokenEnv) != "" {
+			credential = "the token in " + cfg.TokenEnv + " (azure.token_env)"
+		}
+	case cfg.APIKey != "":
+		opts = append(opts, withoutAuthorization(), option.WithHeader("api-key", cfg.APIKey))
+		credential = "AZURE_OPENAI_API_KEY or azure.api_key"
+	default:
+		return nil, fmt.Errorf("azure credentials not set (set AZURE_OPENAI_API_KEY, azure.api_key, azure.token_env or azure.token_command)")
+	}
+
+	// Azure routes on the deployment; the model field is informational.
+	o := newOpenAICompatible("azure", cfg.Deployment, false, opts...)
+	o.noStreamUsage = cfg.NoStreamUsage
+	o.credential = credential
+	return o, nil
+}
+
+// withAzureToken authenticates requests with a bearer token. The token is
+// fetched on the first request rather than in NewAzure, so token_command
+// only runs when azure is actually used, not whenever it is listed in a
+// fallback chain.
+func withAzureToken(cfg config.AzureConfig) option.RequestOption {
+	token := sync.OnceValues(func() (string, error) { return azureToken(cfg) })
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		t, err := token()
+		if err != nil {
+			return nil, &Error{Kind: ErrAuth, Provider: "azure", Credential: "azure.token_command", Err: err}
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return next(req)
+	})
+}
+
+// azureToken returns a bearer token from token_env or, failing that,
+// token_command.
+func azureToken(cfg config.AzureConfig) (string, error) {
+	if cfg.TokenEnv != "" {
+		if token := os.Getenv(cfg.TokenEnv); token != "" {
+			return token, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", cfg.TokenCommand)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("azure token_command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("azure token_command returned an empty token")
+	}
+	return token, nil
+}
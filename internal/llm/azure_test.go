@@ -0,0 +1,225 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// azureServer mimics the Azure OpenAI chat completions endpoint: it only
+// answers on the deployment path with an api-version and valid credentials.
+func azureServer(t *testing.T, deployment string, authorized func(http.Header) bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/openai/deployments/"+deployment+"/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"DeploymentNotFound","message":"The API deployment for this resource does not exist."}}`))
+			return
+		}
+		if r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"404","message":"Resource not found"}}`))
+			return
+		}
+		if !authorized(r.Header) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":"401","message":"Access denied due to invalid subscription key."}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":0,"model":"gpt-4o",
+			"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant",
+			"content":"{\"command\":\"df -h\",\"explanation\":\"Show disk usage\",\"alternatives\":[],\"warnings\":[]}"}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAzureAPIKey(t *testing.T) {
+	srv := azureServer(t, "gpt4o-prod", func(h http.Header) bool {
+		return h.Get("api-key") == "azure-key" && h.Get("Authorization") == ""
+	})
+	t.Setenv("OPENAI_API_KEY", "sk-should-not-be-sent")
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:   srv.URL + "/",
+		Deployment: "gpt4o-prod",
+		APIVersion: "2024-10-21",
+		APIKey:     "azure-key",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+
+	resp, err := provider.Complete(context.Background(), NewRequest("system", "disk usage"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if resp.Command != "df -h" {
+		t.Errorf("command: got %q, want %q", resp.Command, "df -h")
+	}
+}
+
+func TestAzureBearerTokenCommand(t *testing.T) {
+	srv := azureServer(t, "dep", func(h http.Header) bool {
+		return h.Get("Authorization") == "Bearer entra-token"
+	})
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:     srv.URL,
+		Deployment:   "dep",
+		APIVersion:   "2024-10-21",
+		TokenCommand: "echo entra-token",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+}
+
+func TestAzureTokenCommandIsLazy(t *testing.T) {
+	srv := azureServer(t, "dep", func(h http.Header) bool {
+		return h.Get("Authorization") == "Bearer entra-token"
+	})
+	calls := filepath.Join(t.TempDir(), "calls")
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:     srv.URL,
+		Deployment:   "dep",
+		APIVersion:   "2024-10-21",
+		TokenCommand: "echo x >> " + calls + " && echo entra-token",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+	if _, err := os.Stat(calls); err == nil {
+		t.Fatal("token_command ran before the first request")
+	}
+
+	for range 2 {
+		if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+			t.Fatalf("Complete error: %v", err)
+		}
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("token_command ran %d times, want once", n)
+	}
+}
+
+func TestAzureTokenCommandFails(t *testing.T) {
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:     "http://127.0.0.1:1",
+		Deployment:   "dep",
+		TokenCommand: "exit 1",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+	_, err = provider.Complete(context.Background(), NewRequest("system", "q"))
+	if KindOf(err) != ErrAuth || !strings.Contains(err.Error(), "token_command") {
+		t.Errorf("expected an auth error from token_command, got %v", err)
+	}
+}
+
+func TestAzureBearerTokenEnv(t *testing.T) {
+	srv := azureServer(t, "dep", func(h http.Header) bool {
+		return h.Get("Authorization") == "Bearer env-token"
+	})
+	t.Setenv("AZURE_TOKEN", "env-token")
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:   srv.URL,
+		Deployment: "dep",
+		APIVersion: "2024-10-21",
+		TokenEnv:   "AZURE_TOKEN",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+}
+
+func TestAzureInvalidKey(t *testing.T) {
+	srv := azureServer(t, "dep", func(h http.Header) bool { return false })
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:   srv.URL,
+		Deployment: "dep",
+		APIVersion: "2024-10-21",
+		APIKey:     "wrong",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+
+	_, err = provider.Complete(context.Background(), NewRequest("system", "q"))
+	if KindOf(err) != ErrAuth {
+		t.Errorf("expected auth error, got %v", err)
+	}
+	var perr *Error
+	if errors.As(err, &perr) && perr.Credential != "AZURE_OPENAI_API_KEY or azure.api_key" {
+		t.Errorf("Credential = %q, want the api key", perr.Credential)
+	}
+}
+
+func TestAzureInvalidToken(t *testing.T) {
+	srv := azureServer(t, "dep", func(h http.Header) bool { return false })
+	t.Setenv("AZURE_TOKEN", "expired")
+
+	provider, err := NewAzure(config.AzureConfig{
+		Endpoint:   srv.URL,
+		Deployment: "dep",
+		APIVersion: "2024-10-21",
+		APIKey:     "unused",
+		TokenEnv:   "AZURE_TOKEN",
+	})
+	if err != nil {
+		t.Fatalf("NewAzure error: %v", err)
+	}
+
+	_, err = provider.Complete(context.Background(), NewRequest("system", "q"))
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Kind != ErrAuth || !strings.Contains(perr.Credential, "AZURE_TOKEN") {
+		t.Errorf("expected an auth error naming AZURE_TOKEN, got %v", err)
+	}
+}
+
+func TestAzureConfigErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.AzureConfig
+		want string
+	}{
+		{name: "no endpoint", cfg: config.AzureConfig{Deployment: "d", APIKey: "k"}, want: "endpoint"},
+		{name: "no deployment", cfg: config.AzureConfig{Endpoint: "https://x", APIKey: "k"}, want: "deployment"},
+		{name: "no credentials", cfg: config.AzureConfig{Endpoint: "https://x", Deployment: "d"}, want: "credentials"},
+		{name: "empty token env", cfg: config.AzureConfig{Endpoint: "https://x", Deployment: "d", TokenEnv: "HOW_TEST_UNSET_TOKEN"}, want: "token_env"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAzure(tc.cfg)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %q in error, got: %v", tc.want, err)
+			}
+		})
+	}
+}
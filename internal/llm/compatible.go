@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/swibrow/how/internal/config"
+)
+
+// OpenAICompatible talks to any server implementing the OpenAI chat
+// completions API, such as LM Studio, vLLM, LiteLLM or an internal gateway.
+type OpenAICompatible struct {
+	name      string
+	client    *openai.Client
+	model     string
+	plainText bool
+	// noStreamUsage leaves stream_options out of streamed requests.
+	noStreamUsage bool
+	// credential names the source of the API key or token, for auth errors.
+	credential string
+}
+
+// NewOpenAICompatible creates a client for the named instance. The name is
+// used in error messages and to report which provider answered.
+func NewOpenAICompatible(name string, cfg config.OpenAICompatibleConfig) (*OpenAICompatible, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("%s: base_url not set (configure openai_compatible.%s.base_url in ~/.config/how/config.yaml)", name, name)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("%s: model not set (configure openai_compatible.%s.model in ~/.config/how/config.yaml)", name, name)
+	}
+
+	apiKey := cfg.APIKey
+	credential := "openai_compatible." + name + ".api_key"
+	if cfg.APIKeyEnv != "" {
+		if key := os.Getenv(cfg.APIKeyEnv); key != "" {
+			apiKey = key
+			credential = cfg.APIKeyEnv
+		}
+	}
+
+	opts := []option.RequestOption{option.WithBaseURL(cfg.BaseURL)}
+	if apiKey != "" {
+		opts = append(opts, option.WithAPIKey(apiKey))
+	} else {
+		opts = append(opts, withoutAuthorization())
+	}
+	for k, v := range cfg.Headers {
+		opts = append(opts, option.WithHeader(k, v))
+	}
+
+	o := newOpenAICompatible(name, cfg.Model, cfg.PlainText, opts...)
+	o.noStreamUsage = cfg.NoStreamUsage
+	o.credential = credential
+	return o, nil
+}
+
+// newOpenAICompatible builds a client with the given request options on top
+// of the shared defaults.
+func newOpenAICompatible(name, model string, plainText bool, opts ...option.RequestOption) *OpenAICompatible {
+	base := []option.RequestOption{
+		option.WithMaxRetries(0),
+		// The SDK picks up OpenAI credentials from the environment; never
+		// send those to a third-party server.
+		option.WithHeaderDel("OpenAI-Organization"),
+		option.WithHeaderDel("OpenAI-Project"),
+	}
+	client := openai.NewClient(append(base, opts...)...)
+
+	return &OpenAICompatible{
+		name:      name,
+		client:    &client,
+		model:     model,
+		plainText: plainText,
+	}
+}
+
+// withoutAuthorization drops the bearer token the SDK would otherwise
+// derive from OPENAI_API_KEY.
+func withoutAuthorization() option.RequestOption {
+	return option.WithHeaderDel("Authorization")
+}
+
+func (o *OpenAICompatible) params(req Request) openai.ChatCompletionNewParams {
+	params := chatParams(o.model, req)
+	if o.plainText {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{}
+	}
+	return params
+}
+
+func (o *OpenAICompatible) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := o.client.Chat.Completions.New(ctx, o.params(req))
+	if err != nil {
+		return nil, o.classify(err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%s returned no choices", o.name)
+	}
+
+	return withUsage(decodeResponse(resp.Choices[0].Message.Content), chatUsage(resp.Usage)), nil
+}
+
+func (o *OpenAICompatible) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	params := o.params(req)
+	if !o.noStreamUsage {
+		params.StreamOptions = streamUsage
+	}
+	text, usage, err := streamChat(ctx, o.client, params, onChunk)
+	if err != nil {
+		return nil, o.classify(err)
+	}
+	return withUsage(decodeResponse(text), usage), nil
+}
+
+// Models implements ModelLister using the server's /models endpoint.
+func (o *OpenAICompatible) Models(ctx context.Context) ([]string, error) {
+	models, err := listModels(ctx, o.client)
+	if err != nil {
+		return nil, o.classify(err)
+	}
+	return models, nil
+}
+
+// classify wraps err as a provider error, noting the credential source
+// when the server rejected it.
+func (o *OpenAICompatible) classify(err error) error {
+	err = wrapError(o.name, err)
+	var perr *Error
+	if errors.As(err, &perr) && perr.Kind == ErrAuth && perr.Credential == "" {
+		perr.Credential = o.credential
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// chatServer answers chat completion requests with content and records the
+// last request's headers and body.
+type chatServer struct {
+	*httptest.Server
+	header http.Header
+	body   map[string]any
+	path   string
+}
+
+func newChatServer(t *testing.T, content string) *chatServer {
+	t.Helper()
+	cs := &chatServer{}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.header = r.Header.Clone()
+		cs.path = r.URL.Path
+		cs.body = nil
+		if err := json.NewDecoder(r.Body).Decode(&cs.body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "1", "object": "chat.completion", "created": 0, "model": "m",
+			"choices": []any{map[string]any{
+				"index": 0, "finish_reason": "stop",
+				"message": map[string]any{"role": "assistant", "content": content},
+			}},
+		})
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+func TestOpenAICompatibleRequest(t *testing.T) {
+	srv := newChatServer(t, "COMMAND: ls\nEXPLANATION: List")
+	t.Setenv("GATEWAY_KEY", "env-key")
+
+	provider, err := NewOpenAICompatible("gateway", config.OpenAICompatibleConfig{
+		BaseURL:   srv.URL + "/v1",
+		APIKeyEnv: "GATEWAY_KEY",
+		Headers:   map[string]string{"X-Team": "infra"},
+		Model:     "qwen2.5-coder",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatible error: %v", err)
+	}
+
+	resp, err := provider.Complete(context.Background(), NewRequest("system", "list files"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	if srv.path != "/v1/chat/completions" {
+		t.Errorf("path: got %q", srv.path)
+	}
+	if got := srv.header.Get("Authorization"); got != "Bearer env-key" {
+		t.Errorf("authorization: got %q", got)
+	}
+	if got := srv.header.Get("X-Team"); got != "infra" {
+		t.Errorf("extra header: got %q", got)
+	}
+	if srv.body["model"] != "qwen2.5-coder" {
+		t.Errorf("model: got %v", srv.body["model"])
+	}
+	if resp.Text != "COMMAND: ls\nEXPLANATION: List" {
+		t.Errorf("text: got %q", resp.Text)
+	}
+}
+
+func TestOpenAICompatibleNoKeyOmitsAuthorization(t *testing.T) {
+	srv := newChatServer(t, "COMMAND: ls")
+	t.Setenv("OPENAI_API_KEY", "sk-should-not-leak")
+
+	provider, err := NewOpenAICompatible("lmstudio", config.OpenAICompatibleConfig{
+		BaseURL: srv.URL,
+		Model:   "local-model",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatible error: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	if got := srv.header.Get("Authorization"); got != "" {
+		t.Errorf("expected no authorization header, got %q", got)
+	}
+}
+
+func TestOpenAICompatiblePlainText(t *testing.T) {
+	srv := newChatServer(t, "COMMAND: ls")
+
+	provider, err := NewOpenAICompatible("old", config.OpenAICompatibleConfig{
+		BaseURL:   srv.URL,
+		Model:     "m",
+		PlainText: true,
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatible error: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	if _, ok := srv.body["response_format"]; ok {
+		t.Errorf("plain_text should omit response_format, got %v", srv.body["response_format"])
+	}
+}
+
+func TestOpenAICompatibleRequiresBaseURLAndModel(t *testing.T) {
+	if _, err := NewOpenAICompatible("x", config.OpenAICompatibleConfig{Model: "m"}); err == nil {
+		t.Error("expected error without base_url")
+	}
+	if _, err := NewOpenAICompatible("x", config.OpenAICompatibleConfig{BaseURL: "http://localhost"}); err == nil {
+		t.Error("expected error without model")
+	}
+}
+
+func TestNewProviderNamedCompatible(t *testing.T) {
+	srv := newChatServer(t, "COMMAND: ls")
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = "vllm"
+	cfg.Compatible = map[string]config.OpenAICompatibleConfig{
+		"vllm": {BaseURL: srv.URL, Model: "llama-3-70b"},
+	}
+
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewProvider error: %v", err)
+	}
+	if _, err := provider.Complete(context.Background(), NewRequest("system", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if got := ModelName(cfg, "vllm"); got != "llama-3-70b" {
+		t.Errorf("ModelName: got %q", got)
+	}
+}
+
+func TestIsLocal(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Compatible = map[string]config.OpenAICompatibleConfig{
+		"lmstudio": {BaseURL: "http://localhost:1234/v1"},
+		"vllm":     {BaseURL: "http://127.0.0.1:8000/v1"},
+		"gateway":  {BaseURL: "https://llm.internal.example.com/v1"},
+	}
+
+	for name, want := range map[string]bool{
+		"anthropic": false,
+		"gemini":    false,
+		"ollama":    true,
+		"llamacpp":  true,
+		"lmstudio":  true,
+		"vllm":      true,
+		"gateway":   false,
+		"unknown":   false,
+	} {
+		if got := IsLocal(cfg, name); got != want {
+			t.Errorf("IsLocal(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestOpenAICompatibleNoStreamUsage(t *testing.T) {
+	// An older server that rejects stream_options.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if _, ok := body["stream_options"]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Unrecognized request argument supplied: stream_options"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","object":"chat.completion.chunk","created":0,"model":"m","choices":[{"index":0,"delta":{"content":"COMMAND: ls"}}]}` + "\n\ndata: [DONE]\n\n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, noUsage := range []bool{false, true} {
+		provider, err := NewOpenAICompatible("old", config.OpenAICompatibleConfig{
+			BaseURL:       srv.URL,
+			Model:         "m",
+			NoStreamUsage: noUsage,
+		})
+		if err != nil {
+			t.Fatalf("NewOpenAICompatible error: %v", err)
+		}
+		_, err = provider.Stream(context.Background(), NewRequest("system", "q"), func(string) {})
+		if noUsage && err != nil {
+			t.Errorf("no_stream_usage: Stream error: %v", err)
+		}
+		if !noUsage && KindOf(err) != ErrBadRequest {
+			t.Errorf("expected the server to reject stream_options, got %v", err)
+		}
+	}
+}
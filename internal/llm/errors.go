@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// ErrorKind classifies provider failures so callers can decide whether to
+// retry, fail over, or tell the user how to fix their setup.
+type ErrorKind int
+
+const (
+	ErrUnknown ErrorKind = iota
+	ErrAuth
+	ErrRateLimit
+	ErrNetwork
+	ErrTimeout
+	ErrBadRequest
+	ErrServer
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrAuth:
+		return "auth"
+	case ErrRateLimit:
+		return "rate limit"
+	case ErrNetwork:
+		return "network"
+	case ErrTimeout:
+		return "timeout"
+	case ErrBadRequest:
+		return "bad request"
+	case ErrServer:
+		return "server"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is a classified failure from a provider.
+type Error struct {
+	Kind       ErrorKind
+	Provider   string
+	StatusCode int
+	// RetryAfter is the delay requested by the server, if any.
+	RetryAfter time.Duration
+	// Credential names where the rejected credential came from, such as
+	// an environment variable or config key, when the provider knows.
+	Credential string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s API error: %v", e.Provider, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Transient reports whether the same request may succeed if tried again
+// later or elsewhere.
+func (e *Error) Transient() bool {
+	switch e.Kind {
+	case ErrRateLimit, ErrNetwork, ErrTimeout, ErrServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// KindOf returns the kind of a provider error, or ErrUnknown if err is not
+// an *Error.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ErrUnknown
+}
+
+// wrapError classifies err from the named provider. Cancellation by the
+// caller is passed through unchanged.
+func wrapError(provider string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	var existing *Error
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	e := &Error{Provider: provider, Err: err}
+
+	var anthropicErr *anthropic.Error
+	var openaiErr *openai.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Kind = ErrTimeout
+	case errors.As(err, &anthropicErr):
+		e.StatusCode = anthropicErr.StatusCode
+		e.RetryAfter = retryAfter(anthropicErr.Response)
+	case errors.As(err, &openaiErr):
+		e.StatusCode = openaiErr.StatusCode
+		e.RetryAfter = retryAfter(openaiErr.Response)
+	case errors.As(err, &netErr):
+		e.Kind = ErrNetwork
+		if netErr.Timeout() {
+			e.Kind = ErrTimeout
+		}
+	}
+	if e.StatusCode != 0 {
+		e.Kind = kindForStatus(e.StatusCode)
+	}
+	return e
+}
+
+// statusError builds an *Error from an HTTP response for backends that talk
+// to their API without an SDK.
+func statusError(provider string, resp *http.Response, msg string) error {
+	return &Error{
+		Kind:       kindForStatus(resp.StatusCode),
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter(resp),
+		Err:        fmt.Errorf("%s: %s", resp.Status, msg),
+	}
+}
+
+func kindForStatus(code int) ErrorKind {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrAuth
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case code == http.StatusRequestTimeout:
+		return ErrTimeout
+	case code >= 500:
+		return ErrServer
+	case code >= 400:
+		return ErrBadRequest
+	default:
+		return ErrUnknown
+	}
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
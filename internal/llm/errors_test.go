@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+func TestWrapErrorClassifies(t *testing.T) {
+	rateLimited := &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{"7"}}}
+
+	cases := []struct {
+		name       string
+		err        error
+		want       ErrorKind
+		retryAfter time.Duration
+	}{
+		{name: "anthropic unauthorized", err: &anthropic.Error{StatusCode: 401}, want: ErrAuth},
+		{name: "openai forbidden", err: &openai.Error{StatusCode: 403}, want: ErrAuth},
+		{name: "rate limited", err: &anthropic.Error{StatusCode: 429, Response: rateLimited}, want: ErrRateLimit, retryAfter: 7 * time.Second},
+		{name: "bad request", err: &openai.Error{StatusCode: 400}, want: ErrBadRequest},
+		{name: "not found", err: &openai.Error{StatusCode: 404}, want: ErrBadRequest},
+		{name: "server error", err: &anthropic.Error{StatusCode: 529}, want: ErrServer},
+		{name: "deadline", err: context.DeadlineExceeded, want: ErrTimeout},
+		{name: "dial error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: ErrNetwork},
+		{name: "other", err: errors.New("boom"), want: ErrUnknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := wrapError("test", tc.err)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *Error, got %T", err)
+			}
+			if perr.Kind != tc.want {
+				t.Errorf("kind: got %v, want %v", perr.Kind, tc.want)
+			}
+			if perr.RetryAfter != tc.retryAfter {
+				t.Errorf("retry after: got %v, want %v", perr.RetryAfter, tc.retryAfter)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Error("wrapped error should unwrap to the original")
+			}
+		})
+	}
+}
+
+func TestWrapErrorPassesThroughCancellation(t *testing.T) {
+	if err := wrapError("test", context.Canceled); err != context.Canceled {
+		t.Errorf("expected context.Canceled unchanged, got %v", err)
+	}
+	if err := wrapError("test", nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := wrapError("anthropic", errors.New("boom"))
+	if err.Error() != "anthropic API error: boom" {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+}
+
+func TestRetryAfterHTTPDate(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat))
+
+	got := retryAfter(resp)
+	if got <= 20*time.Second || got > 30*time.Second {
+		t.Errorf("retryAfter: got %v, want about 30s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// namedProvider pairs a backend with the config name it was built from.
+type namedProvider struct {
+	name     string
+	provider Provider
+}
+
+// Fallback tries an ordered list of providers, moving on to the next one
+// when a request fails in a way another backend might not (network errors,
+// timeouts, rate limits and server errors). Other errors, such as a bad
+// request, are returned immediately.
+type Fallback struct {
+	providers []namedProvider
+	skipped   []error
+
+	// OnFailover, if set, is called with the provider name and error each
+	// time a backend fails and the next one is tried.
+	OnFailover func(name string, err error)
+}
+
+// Skipped returns why each configured backend left out of the chain could
+// not be built, such as a missing API key.
+func (f *Fallback) Skipped() []error {
+	return f.skipped
+}
+
+// Complete implements Provider. The returned Response records which
+// backend answered in its Provider field.
+func (f *Fallback) Complete(ctx context.Context, req Request) (*Response, error) {
+	return f.try(ctx, func(p Provider) (*Response, bool, error) {
+		resp, err := p.Complete(ctx, req)
+		return resp, false, err
+	})
+}
+
+// Stream implements StreamingProvider. Backends without streaming support
+// are called through Complete. Once a backend has streamed any output the
+// chain stops, since that output has already been shown.
+func (f *Fallback) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	return f.try(ctx, func(p Provider) (*Response, bool, error) {
+		sp, ok := p.(StreamingProvider)
+		if !ok {
+			resp, err := p.Complete(ctx, req)
+			return resp, false, err
+		}
+		streamed := false
+		resp, err := sp.Stream(ctx, req, func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		})
+		return resp, streamed, err
+	})
+}
+
+func (f *Fallback) try(ctx context.Context, call func(Provider) (*Response, bool, error)) (*Response, error) {
+	var errs []error
+	for i, np := range f.providers {
+		resp, streamed, err := call(np.provider)
+		if err == nil {
+			resp.Provider = np.name
+			return resp, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", np.name, err))
+		last := i == len(f.providers)-1
+		if last || streamed || ctx.Err() != nil || !shouldFailover(err) {
+			break
+		}
+		if f.OnFailover != nil {
+			f.OnFailover(np.name, err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// shouldFailover reports whether err is worth retrying on another backend:
+// network failures, timeouts, HTTP 429 and 5xx responses.
+func shouldFailover(err error) bool {
+	var perr *Error
+	return errors.As(err, &perr) && perr.Transient()
+}
+
+// providerFactory builds a single backend by name.
+type providerFactory func(name string) (Provider, error)
+
+// newFallback builds a chain from provider names.
+func newFallback(factory providerFactory, names []string) (Provider, error) {
+	providers, skipped, err := buildProviders(factory, names)
+	if err != nil {
+		return nil, err
+	}
+	return &Fallback{providers: providers, skipped: skipped}, nil
+}
+
+// buildProviders builds the named backends. Backends that cannot be
+// constructed (e.g. a missing API key) are skipped so the rest still work,
+// and their errors returned so the caller can report them; it is an error
+// only if none can be built.
+func buildProviders(factory providerFactory, names []string) ([]namedProvider, []error, error) {
+	var providers []namedProvider
+	var errs []error
+	for _, name := range names {
+		p, err := factory(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		providers = append(providers, namedProvider{name: name, provider: p})
+	}
+	if len(providers) == 0 {
+		return nil, nil, fmt.Errorf("no usable provider in [%s]: %w", strings.Join(names, ", "), errors.Join(errs...))
+	}
+	return providers, errs, nil
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// fakeProvider returns a fixed response or error and counts its calls.
+type fakeProvider struct {
+	resp   *Response
+	err    error
+	chunks []string
+	calls  int
+}
+
+func (f *fakeProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	return &resp, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	for _, c := range f.chunks {
+		onChunk(c)
+	}
+	return f.Complete(ctx, req)
+}
+
+func newTestFallback(providers ...*fakeProvider) *Fallback {
+	f := &Fallback{}
+	for i, p := range providers {
+		f.providers = append(f.providers, namedProvider{name: string(rune('a' + i)), provider: p})
+	}
+	return f
+}
+
+func TestFallbackFailsOverOnRetryableErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{name: "rate limited", err: &Error{Kind: ErrRateLimit}},
+		{name: "server error", err: &Error{Kind: ErrServer}},
+		{name: "network", err: wrapError("a", &net.OpError{Op: "dial", Err: errors.New("connection refused")})},
+		{name: "timeout", err: wrapError("a", context.DeadlineExceeded)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			first := &fakeProvider{err: tc.err}
+			second := &fakeProvider{resp: &Response{Command: "ls"}}
+			f := newTestFallback(first, second)
+
+			var failed []string
+			f.OnFailover = func(name string, err error) { failed = append(failed, name) }
+
+			resp, err := f.Complete(context.Background(), NewRequest("sys", "q"))
+			if err != nil {
+				t.Fatalf("Complete error: %v", err)
+			}
+			if resp.Provider != "b" {
+				t.Errorf("provider: got %q, want %q", resp.Provider, "b")
+			}
+			if len(failed) != 1 || failed[0] != "a" {
+				t.Errorf("OnFailover calls: got %v", failed)
+			}
+		})
+	}
+}
+
+func TestFallbackStopsOnBadRequest(t *testing.T) {
+	first := &fakeProvider{err: &Error{Kind: ErrBadRequest}}
+	second := &fakeProvider{resp: &Response{Command: "ls"}}
+	f := newTestFallback(first, second)
+
+	if _, err := f.Complete(context.Background(), NewRequest("sys", "q")); err == nil {
+		t.Fatal("expected error for bad request")
+	}
+	if second.calls != 0 {
+		t.Errorf("second provider should not be called, got %d calls", second.calls)
+	}
+}
+
+func TestFallbackAllFail(t *testing.T) {
+	f := newTestFallback(
+		&fakeProvider{err: &Error{Kind: ErrServer}},
+		&fakeProvider{err: &Error{Kind: ErrServer}},
+	)
+
+	_, err := f.Complete(context.Background(), NewRequest("sys", "q"))
+	if err == nil {
+		t.Fatal("expected error when every provider fails")
+	}
+	if !strings.Contains(err.Error(), "a:") || !strings.Contains(err.Error(), "b:") {
+		t.Errorf("expected both failures in error, got: %v", err)
+	}
+}
+
+func TestFallbackStreamStopsAfterOutput(t *testing.T) {
+	first := &fakeProvider{err: &Error{Kind: ErrServer}, chunks: []string{"COMMAND: l"}}
+	second := &fakeProvider{resp: &Response{Command: "ls"}}
+	f := newTestFallback(first, second)
+
+	_, err := f.Stream(context.Background(), NewRequest("sys", "q"), func(string) {})
+	if err == nil {
+		t.Fatal("expected error once output was streamed")
+	}
+	if second.calls != 0 {
+		t.Errorf("second provider should not be called after streamed output, got %d calls", second.calls)
+	}
+}
+
+func TestNewProviderChainSkipsMisconfigured(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers = []string{"anthropic", "ollama"}
+	cfg.Anthropic.APIKey = ""
+
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewProvider error: %v", err)
+	}
+	fb, ok := provider.(*Fallback)
+	if !ok {
+		t.Fatalf("expected *Fallback, got %T", provider)
+	}
+	if len(fb.providers) != 1 || fb.providers[0].name != "ollama" {
+		t.Errorf("expected only ollama in chain, got %+v", fb.providers)
+	}
+	skipped := fb.Skipped()
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0].Error(), "anthropic: ") {
+		t.Errorf("Skipped() = %v, want the anthropic error", skipped)
+	}
+}
+
+func TestNewProviderChainNoneUsable(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers = []string{"anthropic", "bogus"}
+	cfg.Anthropic.APIKey = ""
+
+	if _, err := NewProvider(cfg); err == nil {
+		t.Fatal("expected error when no provider in the chain can be built")
+	}
+}
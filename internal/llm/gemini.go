@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// Gemini talks to the Google Gemini generateContent REST API.
+type Gemini struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewGemini(cfg config.GeminiConfig) (*Gemini, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("gemini API key not set (set GEMINI_API_KEY or configure in ~/.config/how/config.yaml)")
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("gemini model not set (configure gemini.model in ~/.config/how/config.yaml)")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+
+	return &Gemini{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  cfg.APIKey,
+		model:   cfg.Model,
+		client:  http.DefaultClient,
+	}, nil
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	ResponseMIMEType string         `json:"responseMimeType"`
+	ResponseSchema   map[string]any `json:"responseSchema"`
+}
+
+type geminiRequest struct {
+	SystemInstruction geminiContent          `json:"systemInstruction"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// request builds the generateContent body. Gemini calls the assistant role
+// "model" and takes the response schema in its OpenAPI subset.
+func (g *Gemini) request(req Request) geminiRequest {
+	contents := make([]geminiContent, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+
+	return geminiRequest{
+		SystemInstruction: geminiContent{Parts: []geminiPart{{Text: req.SystemPrompt()}}},
+		Contents:          contents,
+		GenerationConfig: geminiGenerationConfig{
+			ResponseMIMEType: "application/json",
+			ResponseSchema:   geminiSchema(responseSchema()),
+		},
+	}
+}
+
+func (g *Gemini) Complete(ctx context.Context, req Request) (*Response, error) {
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", g.baseURL, url.PathEscape(g.model))
+	header := http.Header{"X-Goog-Api-Key": {g.apiKey}}
+
+	body, err := postJSON(ctx, g.client, "gemini", endpoint, header, g.request(req))
+	if err != nil {
+		return nil, geminiAuthError(err)
+	}
+	defer body.Close() //nolint:errcheck
+
+	var resp geminiResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, wrapError("gemini", fmt.Errorf("decoding response: %w", err))
+	}
+	if reason := resp.PromptFeedback.BlockReason; reason != "" {
+		return nil, &Error{Kind: ErrBadRequest, Provider: "gemini", Err: fmt.Errorf("prompt blocked: %s", reason)}
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, wrapError("gemini", fmt.Errorf("no candidates in response"))
+	}
+
+	candidate := resp.Candidates[0]
+	var parts []string
+	for _, p := range candidate.Content.Parts {
+		parts = append(parts, p.Text)
+	}
+	text := strings.Join(parts, "")
+	if text == "" && candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
+		return nil, &Error{Kind: ErrBadRequest, Provider: "gemini", Err: fmt.Errorf("no answer: finish reason %s", candidate.FinishReason)}
+	}
+
+	usage := Usage{
+		InputTokens:  resp.UsageMetadata.PromptTokenCount,
+		OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+	}
+	return withUsage(decodeResponse(text), usage), nil
+}
+
+// geminiAuthError reclassifies Gemini's 400 for an invalid API key as an
+// auth failure so the user gets the right hint.
+func geminiAuthError(err error) error {
+	var perr *Error
+	if errors.As(err, &perr) && perr.Kind == ErrBadRequest && strings.Contains(perr.Err.Error(), "API key") {
+		perr.Kind = ErrAuth
+	}
+	return err
+}
+
+// geminiSchema converts a JSON schema to the OpenAPI subset Gemini accepts:
+// upper-case type names and no additionalProperties.
+func geminiSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		switch k {
+		case "additionalProperties":
+			continue
+		case "type":
+			if t, ok := v.(string); ok {
+				v = strings.ToUpper(t)
+			}
+		case "properties":
+			if props, ok := v.(map[string]any); ok {
+				converted := make(map[string]any, len(props))
+				for name, p := range props {
+					if ps, ok := p.(map[string]any); ok {
+						converted[name] = geminiSchema(ps)
+					}
+				}
+				v = converted
+			}
+		case "items":
+			if items, ok := v.(map[string]any); ok {
+				v = geminiSchema(items)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+func newTestGemini(t *testing.T, handler http.HandlerFunc) *Gemini {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	g, err := NewGemini(config.GeminiConfig{
+		APIKey:  "gemini-key",
+		Model:   "gemini-2.5-flash",
+		BaseURL: srv.URL + "/v1beta/",
+	})
+	if err != nil {
+		t.Fatalf("NewGemini error: %v", err)
+	}
+	return g
+}
+
+func TestGeminiComplete(t *testing.T) {
+	var body geminiRequest
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-flash:generateContent" {
+			t.Errorf("path: got %q", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "gemini-key" {
+			t.Errorf("api key header: got %q", got)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[
+			{"text":"{\"command\":\"lsof -i :8080\",\"explanation\":\"Show what holds the port\","},
+			{"text":"\"alternatives\":[{\"command\":\"ss -ltnp\",\"explanation\":\"List listeners\"}],\"warnings\":[]}"}
+		]},"finishReason":"STOP"}]}`)
+	})
+
+	req := NewRequest("system", "what is on port 8080")
+	req.Messages = append(req.Messages,
+		Message{Role: RoleAssistant, Content: "netstat -an"},
+		Message{Role: RoleUser, Content: "use lsof"},
+	)
+	resp, err := g.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if resp.Command != "lsof -i :8080" {
+		t.Errorf("command: got %q", resp.Command)
+	}
+	if len(resp.Alternatives) != 1 || resp.Alternatives[0].Command != "ss -ltnp" {
+		t.Errorf("alternatives: got %+v", resp.Alternatives)
+	}
+
+	if body.SystemInstruction.Parts[0].Text != "system" {
+		t.Errorf("system instruction: got %+v", body.SystemInstruction)
+	}
+	wantRoles := []string{"user", "model", "user"}
+	if len(body.Contents) != len(wantRoles) {
+		t.Fatalf("expected %d contents, got %d", len(wantRoles), len(body.Contents))
+	}
+	for i, role := range wantRoles {
+		if body.Contents[i].Role != role {
+			t.Errorf("contents[%d] role: got %q, want %q", i, body.Contents[i].Role, role)
+		}
+	}
+	if body.GenerationConfig.ResponseMIMEType != "application/json" {
+		t.Errorf("responseMimeType: got %q", body.GenerationConfig.ResponseMIMEType)
+	}
+	if body.GenerationConfig.ResponseSchema["type"] != "OBJECT" {
+		t.Errorf("schema type: got %v", body.GenerationConfig.ResponseSchema["type"])
+	}
+}
+
+func TestGeminiErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   ErrorKind
+	}{
+		{
+			name:   "invalid key",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`,
+			want:   ErrAuth,
+		},
+		{
+			name:   "quota",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`,
+			want:   ErrRateLimit,
+		},
+		{
+			name:   "blocked prompt",
+			status: http.StatusOK,
+			body:   `{"promptFeedback":{"blockReason":"SAFETY"}}`,
+			want:   ErrBadRequest,
+		},
+		{
+			name:   "no answer",
+			status: http.StatusOK,
+			body:   `{"candidates":[{"content":{"parts":[]},"finishReason":"MAX_TOKENS"}]}`,
+			want:   ErrBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+
+			_, err := g.Complete(context.Background(), NewRequest("system", "q"))
+			if got := KindOf(err); got != tc.want {
+				t.Errorf("kind: got %v, want %v (err: %v)", got, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestGeminiSchema(t *testing.T) {
+	schema := geminiSchema(responseSchema())
+	if _, ok := schema["additionalProperties"]; ok {
+		t.Error("additionalProperties should be removed")
+	}
+
+	props := schema["properties"].(map[string]any)
+	alts := props["alternatives"].(map[string]any)
+	if alts["type"] != "ARRAY" {
+		t.Errorf("alternatives type: got %v", alts["type"])
+	}
+	items := alts["items"].(map[string]any)
+	if items["type"] != "OBJECT" {
+		t.Errorf("items type: got %v", items["type"])
+	}
+	if _, ok := items["additionalProperties"]; ok {
+		t.Error("nested additionalProperties should be removed")
+	}
+
+	// The shared schema must not be modified.
+	if responseSchema()["type"] != "object" {
+		t.Error("responseSchema was mutated")
+	}
+}
+
+func TestNewProviderGeminiNoKey(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Provider = "gemini"
+
+	_, err := NewProvider(cfg)
+	if err == nil {
+		t.Fatal("expected error for missing gemini API key")
+	}
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// commandGrammar is a GBNF grammar that only admits the COMMAND:/EXPLANATION:
+// answer format, so even small local models produce output ui.ParseResponse
+// can read.
+const commandGrammar = `root ::= "COMMAND: " line "\n" "EXPLANATION: " line
+line ::= [^\n]+
+`
+
+// LlamaCpp talks to llama.cpp's llama-server through its native /completion
+// endpoint, constraining the output with a grammar.
+type LlamaCpp struct {
+	baseURL  string
+	nPredict int
+	client   *http.Client
+}
+
+func NewLlamaCpp(cfg config.LlamaCppConfig) (*LlamaCpp, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("llamacpp URL not set (configure llamacpp.url in ~/.config/how/config.yaml)")
+	}
+	return &LlamaCpp{
+		baseURL:  strings.TrimSuffix(cfg.URL, "/"),
+		nPredict: cfg.NPredict,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+type llamaCppRequest struct {
+	Prompt      string `json:"prompt"`
+	Grammar     string `json:"grammar"`
+	NPredict    int    `json:"n_predict,omitempty"`
+	Stream      bool   `json:"stream"`
+	CachePrompt bool   `json:"cache_prompt"`
+}
+
+type llamaCppResponse struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	TokensEvaluated int64  `json:"tokens_evaluated"`
+	TokensPredicted int64  `json:"tokens_predicted"`
+}
+
+func (r llamaCppResponse) usage() Usage {
+	return Usage{InputTokens: r.TokensEvaluated, OutputTokens: r.TokensPredicted}
+}
+
+func (l *LlamaCpp) Complete(ctx context.Context, req Request) (*Response, error) {
+	body, err := l.completion(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	var resp llamaCppResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, wrapError("llamacpp", fmt.Errorf("decoding response: %w", err))
+	}
+	return withUsage(decodeResponse(resp.Content), resp.usage()), nil
+}
+
+// Stream reads the server-sent events llama-server emits when streaming.
+func (l *LlamaCpp) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	body, err := l.completion(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	var b strings.Builder
+	var usage Usage
+	err = readNDJSON(body, func(line []byte) (bool, error) {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return false, nil
+		}
+		var chunk llamaCppResponse
+		if err := json.Unmarshal(bytes.TrimSpace(data), &chunk); err != nil {
+			return false, fmt.Errorf("decoding stream: %w", err)
+		}
+		if chunk.Content != "" {
+			b.WriteString(chunk.Content)
+			onChunk(chunk.Content)
+		}
+		if chunk.Stop {
+			usage = chunk.usage()
+		}
+		return chunk.Stop, nil
+	})
+	if err != nil {
+		return nil, wrapError("llamacpp", err)
+	}
+	return withUsage(decodeResponse(b.String()), usage), nil
+}
+
+func (l *LlamaCpp) completion(ctx context.Context, req Request, stream bool) (io.ReadCloser, error) {
+	prompt, err := l.prompt(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return postJSON(ctx, l.client, "llamacpp", l.baseURL+"/completion", nil, llamaCppRequest{
+		Prompt:      prompt,
+		Grammar:     commandGrammar,
+		NPredict:    l.nPredict,
+		Stream:      stream,
+		CachePrompt: true,
+	})
+}
+
+// prompt renders the conversation with the loaded model's chat template via
+// /apply-template. Servers too old to have that endpoint get a plain
+// transcript instead.
+func (l *LlamaCpp) prompt(ctx context.Context, req Request) (string, error) {
+	messages := []chatMessage{{Role: "system", Content: req.SystemPrompt()}}
+	for _, m := range req.Messages {
+		messages = append(messages, chatMessage{Role: string(m.Role), Content: m.Content})
+	}
+
+	body, err := postJSON(ctx, l.client, "llamacpp", l.baseURL+"/apply-template", nil, map[string]any{"messages": messages})
+	var perr *Error
+	if errors.As(err, &perr) && perr.StatusCode == http.StatusNotFound {
+		return plainTranscript(messages), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer body.Close() //nolint:errcheck
+
+	var resp struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return "", wrapError("llamacpp", fmt.Errorf("decoding template: %w", err))
+	}
+	return resp.Prompt, nil
+}
+
+func plainTranscript(messages []chatMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			b.WriteString(m.Content + "\n\n")
+		case string(RoleAssistant):
+			b.WriteString("Assistant: " + m.Content + "\n")
+		default:
+			b.WriteString("User: " + m.Content + "\n")
+		}
+	}
+	b.WriteString("Assistant: ")
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// llamaServer mimics llama-server. Without a template handler it answers
+// /apply-template with 404 like servers that predate the endpoint.
+func llamaServer(t *testing.T, withTemplate bool, completion http.HandlerFunc) *LlamaCpp {
+	t.Helper()
+	mux := http.NewServeMux()
+	if withTemplate {
+		mux.HandleFunc("/apply-template", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Messages []chatMessage `json:"messages"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			var parts []string
+			for _, m := range body.Messages {
+				parts = append(parts, "<|"+m.Role+"|>"+m.Content)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"prompt": strings.Join(parts, "") + "<|assistant|>"})
+		})
+	}
+	mux.HandleFunc("/completion", completion)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	l, err := NewLlamaCpp(config.LlamaCppConfig{URL: srv.URL + "/", NPredict: 128})
+	if err != nil {
+		t.Fatalf("NewLlamaCpp error: %v", err)
+	}
+	return l
+}
+
+func TestLlamaCppComplete(t *testing.T) {
+	var body llamaCppRequest
+	l := llamaServer(t, true, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"content":"COMMAND: tar -xzf archive.tar.gz\nEXPLANATION: Extract a gzipped tarball","stop":true}`)
+	})
+
+	resp, err := l.Complete(context.Background(), NewRequest("system", "extract tar.gz"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if resp.Text != "COMMAND: tar -xzf archive.tar.gz\nEXPLANATION: Extract a gzipped tarball" {
+		t.Errorf("text: got %q", resp.Text)
+	}
+
+	if body.Prompt != "<|system|>system<|user|>extract tar.gz<|assistant|>" {
+		t.Errorf("prompt: got %q", body.Prompt)
+	}
+	if body.Grammar != commandGrammar {
+		t.Errorf("grammar not sent: got %q", body.Grammar)
+	}
+	if body.NPredict != 128 || body.Stream {
+		t.Errorf("unexpected request: %+v", body)
+	}
+}
+
+func TestLlamaCppStream(t *testing.T) {
+	l := llamaServer(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"COMMAND: ", "pwd", "\\nEXPLANATION: ", "Print directory"} {
+			fmt.Fprintf(w, "data: {\"content\":\"%s\",\"stop\":false}\n\n", part)
+		}
+		fmt.Fprint(w, "data: {\"content\":\"\",\"stop\":true}\n\n")
+	})
+
+	var chunks []string
+	resp, err := l.Stream(context.Background(), NewRequest("system", "where am i"), func(c string) {
+		chunks = append(chunks, c)
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	if resp.Text != "COMMAND: pwd\nEXPLANATION: Print directory" {
+		t.Errorf("text: got %q", resp.Text)
+	}
+	if len(chunks) != 4 {
+		t.Errorf("expected 4 chunks, got %d", len(chunks))
+	}
+}
+
+func TestLlamaCppWithoutTemplateEndpoint(t *testing.T) {
+	var body llamaCppRequest
+	l := llamaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"content":"COMMAND: ls\nEXPLANATION: List","stop":true}`)
+	})
+
+	req := NewRequest("system", "list files")
+	req.Messages = append(req.Messages,
+		Message{Role: RoleAssistant, Content: "COMMAND: ls\nEXPLANATION: List"},
+		Message{Role: RoleUser, Content: "include hidden"},
+	)
+	if _, err := l.Complete(context.Background(), req); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	want := "system\n\nUser: list files\nAssistant: COMMAND: ls\nEXPLANATION: List\nUser: include hidden\nAssistant: "
+	if body.Prompt != want {
+		t.Errorf("prompt:\ngot  %q\nwant %q", body.Prompt, want)
+	}
+}
+
+func TestLlamaCppLoading(t *testing.T) {
+	l := llamaServer(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`)
+	})
+
+	_, err := l.Complete(context.Background(), NewRequest("system", "q"))
+	if KindOf(err) != ErrServer {
+		t.Fatalf("expected server error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Loading model") {
+		t.Errorf("expected server message in error, got %q", err.Error())
+	}
+}
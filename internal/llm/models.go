@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// ModelLister is implemented by backends that can list the models they
+// serve.
+type ModelLister interface {
+	Models(ctx context.Context) ([]string, error)
+}
+
+// ListModels returns the models offered by the named provider, sorted.
+// Ollama is always asked through its native /api/tags endpoint, which
+// lists the pulled models.
+func ListModels(ctx context.Context, cfg *config.Config, name string) ([]string, error) {
+	var backend Provider
+	var err error
+	if name == "ollama" {
+		backend, err = NewOllamaNative(cfg.Ollama)
+	} else {
+		backend, err = newBackend(cfg, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := backend.(ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support listing models", name)
+	}
+	models, err := lister.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(models)
+	return models, nil
+}
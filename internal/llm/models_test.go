@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+func TestListModelsOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("path: got %q, want /api/tags", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"qwen2.5-coder:7b"},{"name":"llama3:latest"}]}`)
+	}))
+	defer srv.Close()
+
+	// The OpenAI-compatible URL is used as configured; /v1 is stripped.
+	cfg := config.DefaultConfig()
+	cfg.Ollama.URL = srv.URL + "/v1"
+
+	models, err := ListModels(context.Background(), cfg, "ollama")
+	if err != nil {
+		t.Fatalf("ListModels error: %v", err)
+	}
+	if want := []string{"llama3:latest", "qwen2.5-coder:7b"}; !slices.Equal(models, want) {
+		t.Errorf("models: got %v, want %v", models, want)
+	}
+}
+
+func TestListModelsCompatible(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("path: got %q, want /v1/models", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-4o","object":"model"},{"id":"gpt-4o-mini","object":"model"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Compatible = map[string]config.OpenAICompatibleConfig{
+		"gateway": {BaseURL: srv.URL + "/v1", Model: "gpt-4o"},
+	}
+
+	models, err := ListModels(context.Background(), cfg, "gateway")
+	if err != nil {
+		t.Fatalf("ListModels error: %v", err)
+	}
+	if want := []string{"gpt-4o", "gpt-4o-mini"}; !slices.Equal(models, want) {
+		t.Errorf("models: got %v, want %v", models, want)
+	}
+}
+
+func TestListModelsUnsupported(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Gemini.APIKey = "key"
+
+	if _, err := ListModels(context.Background(), cfg, "gemini"); err == nil {
+		t.Fatal("expected error for a provider that cannot list models")
+	}
+}
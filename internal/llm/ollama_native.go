@@ -0,0 +1,316 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// OllamaNative talks to Ollama's own /api/chat endpoint, which unlike the
+// OpenAI-compatible one accepts model options (num_ctx, temperature, seed)
+// and keep_alive, and reports load errors verbatim.
+type OllamaNative struct {
+	baseURL   string
+	model     string
+	keepAlive string
+	options   map[string]any
+	client    *http.Client
+}
+
+// NewOllamaNative creates a native Ollama client. A trailing /v1 on the
+// configured URL (the OpenAI-compatible path) is ignored.
+func NewOllamaNative(cfg config.OllamaConfig) (*OllamaNative, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("ollama model not set (configure ollama.model in ~/.config/how/config.yaml)")
+	}
+
+	options := map[string]any{}
+	if cfg.Options.NumCtx > 0 {
+		options["num_ctx"] = cfg.Options.NumCtx
+	}
+	if cfg.Options.Temperature != nil {
+		options["temperature"] = *cfg.Options.Temperature
+	}
+	if cfg.Options.Seed != nil {
+		options["seed"] = *cfg.Options.Seed
+	}
+
+	return &OllamaNative{
+		baseURL:   ollamaBaseURL(cfg.URL),
+		model:     cfg.Model,
+		keepAlive: cfg.KeepAlive,
+		options:   options,
+		client:    http.DefaultClient,
+	}, nil
+}
+
+// ollamaBaseURL strips the OpenAI-compatible /v1 suffix from an Ollama URL.
+func ollamaBaseURL(url string) string {
+	url = strings.TrimSuffix(url, "/")
+	return strings.TrimSuffix(url, "/v1")
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []chatMessage  `json:"messages"`
+	Stream    bool           `json:"stream"`
+	Format    any            `json:"format,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	Error           string      `json:"error"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+	EvalCount       int64       `json:"eval_count"`
+}
+
+func (r ollamaChatResponse) usage() Usage {
+	return Usage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount}
+}
+
+func (o *OllamaNative) chatRequest(req Request, stream bool) ollamaChatRequest {
+	messages := []chatMessage{{Role: "system", Content: req.SystemPrompt()}}
+	for _, m := range req.Messages {
+		messages = append(messages, chatMessage{Role: string(m.Role), Content: m.Content})
+	}
+	return ollamaChatRequest{
+		Model:     o.model,
+		Messages:  messages,
+		Stream:    stream,
+		Format:    responseSchema(),
+		Options:   o.options,
+		KeepAlive: o.keepAlive,
+	}
+}
+
+func (o *OllamaNative) Complete(ctx context.Context, req Request) (*Response, error) {
+	body, err := o.post(ctx, "/api/chat", o.chatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	var resp ollamaChatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, wrapError("ollama", fmt.Errorf("decoding response: %w", err))
+	}
+	if resp.Error != "" {
+		return nil, wrapError("ollama", fmt.Errorf("%s", resp.Error))
+	}
+
+	return withUsage(decodeResponse(resp.Message.Content), resp.usage()), nil
+}
+
+func (o *OllamaNative) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	body, err := o.post(ctx, "/api/chat", o.chatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	var b strings.Builder
+	var usage Usage
+	err = readNDJSON(body, func(line []byte) (bool, error) {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return false, fmt.Errorf("decoding stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return false, fmt.Errorf("%s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			b.WriteString(chunk.Message.Content)
+			onChunk(chunk.Message.Content)
+		}
+		if chunk.Done {
+			usage = chunk.usage()
+		}
+		return chunk.Done, nil
+	})
+	if err != nil {
+		return nil, wrapError("ollama", err)
+	}
+
+	return withUsage(decodeResponse(b.String()), usage), nil
+}
+
+// HasModel reports whether the configured model has been pulled. A model
+// without a tag matches its :latest variant.
+func (o *OllamaNative) HasModel(ctx context.Context) (bool, error) {
+	models, err := o.Models(ctx)
+	if err != nil {
+		return false, err
+	}
+	want := withDefaultTag(o.model)
+	for _, m := range models {
+		if withDefaultTag(m) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Models implements ModelLister, returning the pulled models from
+// /api/tags.
+func (o *OllamaNative) Models(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return nil, wrapError("ollama", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("ollama", resp, readErrorMessage(resp.Body))
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, wrapError("ollama", fmt.Errorf("decoding tags: %w", err))
+	}
+
+	models := make([]string, len(tags.Models))
+	for i, m := range tags.Models {
+		models[i] = m.Name
+	}
+	return models, nil
+}
+
+// Model returns the configured model name.
+func (o *OllamaNative) Model() string {
+	return o.model
+}
+
+// PullProgress is one status update from /api/pull. Total and Completed
+// are byte counts for the layer being downloaded, when known.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// Pull downloads the configured model, calling progress with each update.
+func (o *OllamaNative) Pull(ctx context.Context, progress func(PullProgress)) error {
+	body, err := o.post(ctx, "/api/pull", map[string]any{"model": o.model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer body.Close() //nolint:errcheck
+
+	err = readNDJSON(body, func(line []byte) (bool, error) {
+		var p PullProgress
+		if err := json.Unmarshal(line, &p); err != nil {
+			return false, fmt.Errorf("decoding pull status: %w", err)
+		}
+		if p.Error != "" {
+			return false, fmt.Errorf("%s", p.Error)
+		}
+		progress(p)
+		return p.Status == "success", nil
+	})
+	if err != nil {
+		return wrapError("ollama", err)
+	}
+	return nil
+}
+
+// post sends a JSON body and returns the response body for a 200 reply.
+func (o *OllamaNative) post(ctx context.Context, path string, payload any) (io.ReadCloser, error) {
+	return postJSON(ctx, o.client, "ollama", o.baseURL+path, nil, payload)
+}
+
+// postJSON sends payload as JSON with the given extra headers and returns
+// the response body for a 200 reply. Other statuses become an *Error
+// carrying the server's error message.
+func postJSON(ctx context.Context, client *http.Client, provider, url string, header http.Header, payload any) (io.ReadCloser, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, wrapError(provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close() //nolint:errcheck
+		return nil, statusError(provider, resp, readErrorMessage(resp.Body))
+	}
+	return resp.Body, nil
+}
+
+// readNDJSON calls fn for each non-empty line until fn reports done, fn
+// returns an error, or the input ends.
+func readNDJSON(r io.Reader, fn func(line []byte) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		done, err := fn(line)
+		if err != nil || done {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readErrorMessage extracts the message from an {"error": "..."} body,
+// falling back to the raw body text.
+func readErrorMessage(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, 64*1024))
+	var body struct {
+		Error any `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != nil {
+		switch e := body.Error.(type) {
+		case string:
+			return e
+		case map[string]any:
+			if msg, ok := e["message"].(string); ok {
+				return msg
+			}
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func withDefaultTag(model string) string {
+	if strings.Contains(model, ":") {
+		return model
+	}
+	return model + ":latest"
+}
@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+func newTestOllamaNative(t *testing.T, handler http.HandlerFunc) *OllamaNative {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	temp := 0.2
+	seed := 42
+	o, err := NewOllamaNative(config.OllamaConfig{
+		Model:     "llama3",
+		URL:       srv.URL + "/v1",
+		KeepAlive: "10m",
+		Options:   config.OllamaOptions{NumCtx: 8192, Temperature: &temp, Seed: &seed},
+	})
+	if err != nil {
+		t.Fatalf("NewOllamaNative error: %v", err)
+	}
+	return o
+}
+
+func TestOllamaNativeComplete(t *testing.T) {
+	var body map[string]any
+	o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path: got %q, want /api/chat", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"{\"command\":\"du -sh *\",\"explanation\":\"Sizes\",\"alternatives\":[],\"warnings\":[]}"},"done":true}`)
+	})
+
+	resp, err := o.Complete(context.Background(), NewRequest("system", "folder sizes"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if resp.Command != "du -sh *" {
+		t.Errorf("command: got %q", resp.Command)
+	}
+
+	if body["keep_alive"] != "10m" {
+		t.Errorf("keep_alive: got %v", body["keep_alive"])
+	}
+	if body["stream"] != false {
+		t.Errorf("stream: got %v", body["stream"])
+	}
+	options, _ := body["options"].(map[string]any)
+	if options["num_ctx"] != float64(8192) || options["temperature"] != 0.2 || options["seed"] != float64(42) {
+		t.Errorf("options: got %v", options)
+	}
+	if _, ok := body["format"].(map[string]any); !ok {
+		t.Errorf("expected JSON schema format, got %v", body["format"])
+	}
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 2 {
+		t.Errorf("expected system and user messages, got %v", messages)
+	}
+}
+
+func TestOllamaNativeStream(t *testing.T) {
+	o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+		for _, part := range []string{"COMMAND: ", "ls", "\\nEXPLANATION: List"} {
+			fmt.Fprintf(w, "{\"message\":{\"role\":\"assistant\",\"content\":\"%s\"},\"done\":false}\n", part)
+		}
+		fmt.Fprint(w, "{\"message\":{\"role\":\"assistant\",\"content\":\"\"},\"done\":true}\n")
+	})
+
+	var chunks int
+	resp, err := o.Stream(context.Background(), NewRequest("system", "q"), func(string) { chunks++ })
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	if resp.Text != "COMMAND: ls\nEXPLANATION: List" {
+		t.Errorf("text: got %q", resp.Text)
+	}
+	if chunks != 3 {
+		t.Errorf("expected 3 chunks, got %d", chunks)
+	}
+}
+
+func TestOllamaNativeModelNotFound(t *testing.T) {
+	o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama3\" not found, try pulling it first"}`)
+	})
+
+	_, err := o.Complete(context.Background(), NewRequest("system", "q"))
+	if KindOf(err) != ErrBadRequest {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+	if got := err.Error(); got != `ollama API error: 404 Not Found: model "llama3" not found, try pulling it first` {
+		t.Errorf("unexpected message: %q", got)
+	}
+}
+
+func TestOllamaNativeHasModel(t *testing.T) {
+	cases := []struct {
+		name   string
+		models string
+		want   bool
+	}{
+		{name: "latest tag", models: `[{"name":"llama3:latest"}]`, want: true},
+		{name: "other tag only", models: `[{"name":"llama3:70b"}]`, want: false},
+		{name: "none", models: `[]`, want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/tags" {
+					t.Errorf("path: got %q, want /api/tags", r.URL.Path)
+				}
+				fmt.Fprintf(w, `{"models":%s}`, tc.models)
+			})
+
+			got, err := o.HasModel(context.Background())
+			if err != nil {
+				t.Fatalf("HasModel error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("HasModel = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOllamaNativePull(t *testing.T) {
+	o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			t.Errorf("path: got %q, want /api/pull", r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":100}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	})
+
+	var updates []PullProgress
+	if err := o.Pull(context.Background(), func(p PullProgress) { updates = append(updates, p) }); err != nil {
+		t.Fatalf("Pull error: %v", err)
+	}
+	if len(updates) != 4 {
+		t.Fatalf("expected 4 updates, got %d", len(updates))
+	}
+	if updates[1].Completed != 50 || updates[1].Total != 100 {
+		t.Errorf("unexpected progress: %+v", updates[1])
+	}
+}
+
+func TestOllamaNativePullError(t *testing.T) {
+	o := newTestOllamaNative(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	})
+
+	if err := o.Pull(context.Background(), func(PullProgress) {}); err == nil {
+		t.Fatal("expected error from failed pull")
+	}
+}
+
+func TestNewProviderOllamaNative(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Provider = "ollama"
+	cfg.Ollama.Native = true
+
+	provider, err := newBackend(cfg, "ollama")
+	if err != nil {
+		t.Fatalf("newBackend error: %v", err)
+	}
+	if _, ok := provider.(*OllamaNative); !ok {
+		t.Errorf("expected *OllamaNative, got %T", provider)
+	}
+}
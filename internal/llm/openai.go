@@ -3,9 +3,11 @@ package llm
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 	"github.com/swibrow/how/internal/config"
 )
 
@@ -19,7 +21,11 @@ func NewOpenAI(cfg config.OpenAIConfig) (*OpenAI, error) {
 		return nil, fmt.Errorf("openai API key not set (set OPENAI_API_KEY or configure in ~/.config/how/config.yaml)")
 	}
 
-	client := openai.NewClient(option.WithAPIKey(cfg.APIKey))
+	// Retries are handled by Retrying so every backend behaves the same.
+	client := openai.NewClient(
+		option.WithAPIKey(cfg.APIKey),
+		option.WithMaxRetries(0),
+	)
 
 	return &OpenAI{
 		client: &client,
@@ -27,21 +33,112 @@ func NewOpenAI(cfg config.OpenAIConfig) (*OpenAI, error) {
 	}, nil
 }
 
-func (o *OpenAI) Complete(ctx context.Context, systemPrompt, userQuery string) (string, error) {
-	resp, err := o.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: o.model,
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userQuery),
-		},
-	})
+func (o *OpenAI) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := o.client.Chat.Completions.New(ctx, chatParams(o.model, req))
 	if err != nil {
-		return "", fmt.Errorf("openai API error: %w", err)
+		return nil, wrapError("openai", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("openai returned no choices")
+		return nil, fmt.Errorf("openai returned no choices")
+	}
+
+	return withUsage(decodeResponse(resp.Choices[0].Message.Content), chatUsage(resp.Usage)), nil
+}
+
+func (o *OpenAI) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	params := chatParams(o.model, req)
+	params.StreamOptions = streamUsage
+	text, usage, err := streamChat(ctx, o.client, params, onChunk)
+	if err != nil {
+		return nil, wrapError("openai", err)
+	}
+	return withUsage(decodeResponse(text), usage), nil
+}
+
+// Models implements ModelLister using /v1/models.
+func (o *OpenAI) Models(ctx context.Context) ([]string, error) {
+	models, err := listModels(ctx, o.client)
+	if err != nil {
+		return nil, wrapError("openai", err)
 	}
+	return models, nil
+}
+
+// listModels lists the model IDs served by an OpenAI-compatible API.
+func listModels(ctx context.Context, client *openai.Client) ([]string, error) {
+	pager := client.Models.ListAutoPaging(ctx)
+	var models []string
+	for pager.Next() {
+		models = append(models, pager.Current().ID)
+	}
+	return models, pager.Err()
+}
+
+// chatParams builds a chat completion request shared by the OpenAI-compatible
+// backends. A JSON schema response format asks the model for a structured
+// answer; models that ignore it fall back to the text parser.
+func chatParams(model string, req Request) openai.ChatCompletionNewParams {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.Messages)+1)
+	messages = append(messages, openai.SystemMessage(req.SystemPrompt()))
+	for _, m := range req.Messages {
+		if m.Role == RoleAssistant {
+			messages = append(messages, openai.AssistantMessage(m.Content))
+		} else {
+			messages = append(messages, openai.UserMessage(m.Content))
+		}
+	}
+
+	return openai.ChatCompletionNewParams{
+		Model:    model,
+		Messages: messages,
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        suggestToolName,
+					Description: openai.String(suggestToolDescription),
+					Schema:      responseSchema(),
+					Strict:      openai.Bool(true),
+				},
+			},
+		},
+	}
+}
+
+// streamUsage asks for token usage in the final chunk of a stream.
+var streamUsage = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+// streamChat runs a streaming chat completion, forwarding each content delta
+// to onChunk and returning the accumulated text and the usage reported in
+// the final chunk, if params asked for it.
+func streamChat(ctx context.Context, client *openai.Client, params openai.ChatCompletionNewParams, onChunk func(string)) (string, Usage, error) {
+	stream := client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close() //nolint:errcheck
+
+	var b strings.Builder
+	var usage Usage
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
+			usage = chatUsage(chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		b.WriteString(delta)
+		onChunk(delta)
+	}
+	if err := stream.Err(); err != nil {
+		return "", Usage{}, err
+	}
+
+	return b.String(), usage, nil
+}
 
-	return resp.Choices[0].Message.Content, nil
+func chatUsage(u openai.CompletionUsage) Usage {
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
 }
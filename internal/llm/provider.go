@@ -3,25 +3,225 @@ package llm
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"time"
 
 	"github.com/swibrow/how/internal/config"
 )
 
+// Role identifies the author of a conversation message.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is a single turn of the conversation.
+type Message struct {
+	Role    Role
+	Content string
+}
+
+// Request is a conversation to send to a provider: the system prompt plus
+// the user and assistant turns so far, ending with a user message.
+// Alternatives asks for that many extra candidate commands.
+type Request struct {
+	System       string
+	Messages     []Message
+	Alternatives int
+}
+
+// SystemPrompt returns the system prompt to send, including the request
+// for alternatives when the caller asked for any.
+func (r Request) SystemPrompt() string {
+	if r.Alternatives <= 0 {
+		return r.System
+	}
+	return r.System + fmt.Sprintf("\n- Also return %d alternative commands in the alternatives field. "+
+		"Prefer alternatives that use different tools (e.g. ss vs lsof, find vs fd), each with its own explanation.", r.Alternatives)
+}
+
+// NewRequest returns a single-turn request for a question.
+func NewRequest(systemPrompt, question string) Request {
+	return Request{
+		System:   systemPrompt,
+		Messages: []Message{{Role: RoleUser, Content: question}},
+	}
+}
+
 // Provider defines the interface for LLM backends.
 type Provider interface {
-	Complete(ctx context.Context, systemPrompt, userQuery string) (string, error)
+	Complete(ctx context.Context, req Request) (*Response, error)
 }
 
-// NewProvider creates a provider based on the config.
+// StreamingProvider is implemented by backends that can deliver the
+// response incrementally. Stream calls onChunk with each piece of raw
+// output as it arrives and returns the full decoded response.
+type StreamingProvider interface {
+	Provider
+	Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error)
+}
+
+// NewProvider creates a provider based on the config. Each backend is
+// wrapped with the configured timeout and retry policy. When a providers
+// list is configured, the result is a Fallback trying each in order, or a
+// Race asking all at once when strategy is "race".
 func NewProvider(cfg *config.Config) (Provider, error) {
-	switch cfg.Provider {
+	factory := func(name string) (Provider, error) {
+		p, err := newBackend(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		return NewRetrying(p, retryPolicy(cfg, name)), nil
+	}
+	if len(cfg.Providers) > 0 {
+		switch cfg.Strategy {
+		case "", "fallback":
+			return newFallback(factory, cfg.Providers)
+		case "race":
+			return newRace(factory, cfg.Providers)
+		default:
+			return nil, fmt.Errorf("unknown strategy: %s (use fallback or race)", cfg.Strategy)
+		}
+	}
+	return factory(cfg.Provider)
+}
+
+// newBackend creates a single backend by provider name.
+func newBackend(cfg *config.Config, name string) (Provider, error) {
+	switch name {
 	case "anthropic":
 		return NewAnthropic(cfg.Anthropic)
 	case "openai":
 		return NewOpenAI(cfg.OpenAI)
 	case "ollama":
+		if cfg.Ollama.Native {
+			return NewOllamaNative(cfg.Ollama)
+		}
 		return NewOllama(cfg.Ollama)
+	case "azure":
+		return NewAzure(cfg.Azure)
+	case "gemini":
+		return NewGemini(cfg.Gemini)
+	case "llamacpp":
+		return NewLlamaCpp(cfg.LlamaCpp)
+	case "replay":
+		return NewReplay(cfg.Replay)
+	default:
+		if c, ok := cfg.Compatible[name]; ok {
+			return NewOpenAICompatible(name, c)
+		}
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+}
+
+// ModelName returns the model configured for a provider.
+func ModelName(cfg *config.Config, name string) string {
+	switch name {
+	case "anthropic":
+		return cfg.Anthropic.Model
+	case "openai":
+		return cfg.OpenAI.Model
+	case "ollama":
+		return cfg.Ollama.Model
+	case "azure":
+		return cfg.Azure.Deployment
+	case "gemini":
+		return cfg.Gemini.Model
+	case "llamacpp":
+		return cfg.LlamaCpp.Model
 	default:
-		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
+		return cfg.Compatible[name].Model
+	}
+}
+
+// SetModel sets the model the named provider uses. For Azure this is the
+// deployment name.
+func SetModel(cfg *config.Config, name, model string) error {
+	switch name {
+	case "anthropic":
+		cfg.Anthropic.Model = model
+	case "openai":
+		cfg.OpenAI.Model = model
+	case "ollama":
+		cfg.Ollama.Model = model
+	case "azure":
+		cfg.Azure.Deployment = model
+	case "gemini":
+		cfg.Gemini.Model = model
+	case "llamacpp":
+		cfg.LlamaCpp.Model = model
+	default:
+		c, ok := cfg.Compatible[name]
+		if !ok {
+			return fmt.Errorf("unknown provider: %s", name)
+		}
+		c.Model = model
+		cfg.Compatible = maps.Clone(cfg.Compatible)
+		cfg.Compatible[name] = c
+	}
+	return nil
+}
+
+// IsLocal reports whether a provider is self-hosted rather than a paid
+// cloud API: Ollama, llama.cpp, or an OpenAI-compatible server on a
+// loopback address.
+func IsLocal(cfg *config.Config, name string) bool {
+	switch name {
+	case "ollama", "llamacpp", "replay":
+		return true
+	case "anthropic", "openai", "azure", "gemini":
+		return false
+	}
+
+	c, ok := cfg.Compatible[name]
+	if !ok {
+		return false
+	}
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// retryPolicy returns the retry policy for a backend, using its own timeout
+// when set and the global one otherwise.
+func retryPolicy(cfg *config.Config, name string) RetryPolicy {
+	policy := RetryPolicy{
+		Timeout:    cfg.Timeout,
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+
+	var timeout time.Duration
+	switch name {
+	case "anthropic":
+		timeout = cfg.Anthropic.Timeout
+	case "openai":
+		timeout = cfg.OpenAI.Timeout
+	case "ollama":
+		timeout = cfg.Ollama.Timeout
+	case "azure":
+		timeout = cfg.Azure.Timeout
+	case "gemini":
+		timeout = cfg.Gemini.Timeout
+	case "llamacpp":
+		timeout = cfg.LlamaCpp.Timeout
+	default:
+		timeout = cfg.Compatible[name].Timeout
+	}
+	if timeout > 0 {
+		policy.Timeout = timeout
 	}
+	return policy
 }
@@ -1,6 +1,11 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -58,3 +63,182 @@ func TestNewProviderOllama(t *testing.T) {
 		t.Fatal("expected non-nil provider for ollama")
 	}
 }
+
+func TestOllamaStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"COMMAND: ls", " -la\\n", "EXPLANATION: List files"} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"llama3\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%s\"}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	provider, err := NewOllama(config.OllamaConfig{Model: "llama3", URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewOllama error: %v", err)
+	}
+
+	var chunks []string
+	resp, err := provider.Stream(context.Background(), NewRequest("system", "list files"), func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	if resp.Text != "COMMAND: ls -la\nEXPLANATION: List files" {
+		t.Errorf("text: got %q", resp.Text)
+	}
+	if resp.Command != "" {
+		t.Errorf("unstructured output should leave Command empty, got %q", resp.Command)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("expected 3 chunks, got %d: %v", len(chunks), chunks)
+	}
+}
+
+func TestOllamaCompleteStructured(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		content := `{"command":"ss -tlnp","explanation":"List listening TCP ports","alternatives":[{"command":"lsof -i -P","explanation":"Uses lsof"}],"warnings":[]}`
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "1", "object": "chat.completion", "created": 0, "model": "llama3",
+			"choices": []any{map[string]any{
+				"index": 0, "finish_reason": "stop",
+				"message": map[string]any{"role": "assistant", "content": content},
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	provider, err := NewOllama(config.OllamaConfig{Model: "llama3", URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewOllama error: %v", err)
+	}
+
+	resp, err := provider.Complete(context.Background(), NewRequest("system", "listening ports"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+
+	format, _ := body["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Errorf("expected json_schema response_format, got %v", body["response_format"])
+	}
+	if resp.Command != "ss -tlnp" {
+		t.Errorf("command: got %q, want %q", resp.Command, "ss -tlnp")
+	}
+	if len(resp.Alternatives) != 1 || resp.Alternatives[0].Command != "lsof -i -P" {
+		t.Errorf("alternatives: got %+v", resp.Alternatives)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	cases := []struct {
+		name         string
+		text         string
+		wantCommand  string
+		wantWarnings int
+	}{
+		{
+			name:        "structured",
+			text:        `{"command":"ls -la","explanation":"List files","alternatives":[],"warnings":[]}`,
+			wantCommand: "ls -la",
+		},
+		{
+			name:         "structured with warnings",
+			text:         `{"command":"rm -rf build","explanation":"Delete build","alternatives":[],"warnings":["Deletes files permanently",""]}`,
+			wantCommand:  "rm -rf build",
+			wantWarnings: 1,
+		},
+		{
+			name:        "fenced json",
+			text:        "```json\n{\"command\":\"pwd\",\"explanation\":\"Print directory\"}\n```",
+			wantCommand: "pwd",
+		},
+		{
+			name:        "plain text",
+			text:        "COMMAND: ls\nEXPLANATION: List",
+			wantCommand: "",
+		},
+		{
+			name:        "invalid json",
+			text:        `{"command": "ls`,
+			wantCommand: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := decodeResponse(tc.text)
+			if resp.Command != tc.wantCommand {
+				t.Errorf("command: got %q, want %q", resp.Command, tc.wantCommand)
+			}
+			if len(resp.Warnings) != tc.wantWarnings {
+				t.Errorf("warnings: got %v, want %d", resp.Warnings, tc.wantWarnings)
+			}
+			if resp.Text != tc.text {
+				t.Errorf("text should be preserved, got %q", resp.Text)
+			}
+		})
+	}
+}
+
+func TestChatParamsMultiTurn(t *testing.T) {
+	req := NewRequest("system", "find large files")
+	req.Messages = append(req.Messages,
+		Message{Role: RoleAssistant, Content: "COMMAND: find . -size +100M"},
+		Message{Role: RoleUser, Content: "use fd instead"},
+	)
+
+	params := chatParams("gpt-4o", req)
+	if len(params.Messages) != 4 {
+		t.Fatalf("expected system + 3 messages, got %d", len(params.Messages))
+	}
+	if params.Messages[0].OfSystem == nil {
+		t.Error("first message should be the system prompt")
+	}
+	if params.Messages[2].OfAssistant == nil {
+		t.Error("third message should be the assistant turn")
+	}
+	if params.Messages[3].OfUser == nil {
+		t.Error("last message should be the refinement from the user")
+	}
+}
+
+func TestRequestSystemPromptAlternatives(t *testing.T) {
+	req := NewRequest("base prompt", "list ports")
+	if req.SystemPrompt() != "base prompt" {
+		t.Errorf("expected unchanged prompt without alternatives, got %q", req.SystemPrompt())
+	}
+
+	req.Alternatives = 2
+	if !strings.Contains(req.SystemPrompt(), "2 alternative commands") {
+		t.Errorf("expected alternatives instruction, got %q", req.SystemPrompt())
+	}
+}
+
+func TestSetModel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Compatible = map[string]config.OpenAICompatibleConfig{"lmstudio": {Model: "a"}}
+	orig := cfg.Compatible
+
+	for _, name := range []string{"anthropic", "ollama", "azure", "lmstudio"} {
+		if err := SetModel(cfg, name, "m-"+name); err != nil {
+			t.Fatalf("SetModel(%s) error: %v", name, err)
+		}
+		if got := ModelName(cfg, name); got != "m-"+name {
+			t.Errorf("ModelName(%s): got %q", name, got)
+		}
+	}
+	if orig["lmstudio"].Model != "a" {
+		t.Error("SetModel modified a map shared with the original config")
+	}
+	if err := SetModel(cfg, "bogus", "x"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Race sends each request to all its providers at once and returns the
+// first valid response, cancelling the others. It trades cost for latency.
+type Race struct {
+	providers []namedProvider
+	skipped   []error
+
+	// Valid reports whether a response is usable. A response that is not
+	// valid does not win, but is returned if no provider does better.
+	// When nil, any response with a command or text is valid.
+	Valid func(*Response) bool
+
+	// OnWin, if set, is called with the winning provider's name and how
+	// long it took to answer.
+	OnWin func(name string, elapsed time.Duration)
+
+	// OnUsage, if set, is called for each participant other than the one
+	// whose response is returned, so that every request sent is recorded.
+	// Participants that failed or were cancelled report no tokens.
+	OnUsage func(name string, usage Usage)
+}
+
+// Skipped returns why each configured backend left out of the race could
+// not be built.
+func (r *Race) Skipped() []error {
+	return r.skipped
+}
+
+// Names returns the providers taking part in each race.
+func (r *Race) Names() []string {
+	names := make([]string, len(r.providers))
+	for i, np := range r.providers {
+		names[i] = np.name
+	}
+	return names
+}
+
+type raceResult struct {
+	index int
+	resp  *Response
+	err   error
+}
+
+// Complete implements Provider. The returned Response records which
+// backend won in its Provider field.
+func (r *Race) Complete(ctx context.Context, req Request) (*Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results := make(chan raceResult, len(r.providers))
+	for i, np := range r.providers {
+		go func() {
+			resp, err := np.provider.Complete(ctx, req)
+			results <- raceResult{index: i, resp: resp, err: err}
+		}()
+	}
+
+	// answered holds each participant's response once it arrives.
+	answered := make([]*Response, len(r.providers))
+	invalid := -1
+	var errs []error
+	for range r.providers {
+		res := <-results
+		name := r.providers[res.index].name
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, res.err))
+			continue
+		}
+		res.resp.Provider = name
+		answered[res.index] = res.resp
+		if !r.valid(res.resp) {
+			if invalid < 0 {
+				invalid = res.index
+			}
+			continue
+		}
+		if r.OnWin != nil {
+			r.OnWin(name, time.Since(start))
+		}
+		r.reportUsage(answered, res.index)
+		return res.resp, nil
+	}
+
+	r.reportUsage(answered, invalid)
+	if invalid >= 0 {
+		return answered[invalid], nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// reportUsage calls OnUsage for every participant except the one at
+// returned, whose usage the caller records from the response.
+func (r *Race) reportUsage(answered []*Response, returned int) {
+	if r.OnUsage == nil {
+		return
+	}
+	for i, np := range r.providers {
+		if i == returned {
+			continue
+		}
+		var usage Usage
+		if answered[i] != nil {
+			usage = answered[i].Usage
+		}
+		r.OnUsage(np.name, usage)
+	}
+}
+
+func (r *Race) valid(resp *Response) bool {
+	if r.Valid != nil {
+		return r.Valid(resp)
+	}
+	return resp.Command != "" || strings.TrimSpace(resp.Text) != ""
+}
+
+// newRace builds a race between the named providers.
+func newRace(factory providerFactory, names []string) (Provider, error) {
+	providers, skipped, err := buildProviders(factory, names)
+	if err != nil {
+		return nil, err
+	}
+	return &Race{providers: providers, skipped: skipped}, nil
+}
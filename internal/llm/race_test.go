@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+// blockingProvider never answers; it returns once its context is cancelled.
+type blockingProvider struct {
+	cancelled chan struct{}
+}
+
+func (b *blockingProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	<-ctx.Done()
+	close(b.cancelled)
+	return nil, ctx.Err()
+}
+
+func newTestRace(providers ...Provider) *Race {
+	r := &Race{}
+	for i, p := range providers {
+		r.providers = append(r.providers, namedProvider{name: string(rune('a' + i)), provider: p})
+	}
+	return r
+}
+
+func TestRaceTakesFirstValidAndCancelsRest(t *testing.T) {
+	slow := &blockingProvider{cancelled: make(chan struct{})}
+	invalid := &fakeProvider{resp: &Response{Text: "I am not sure"}}
+	valid := &fakeProvider{resp: &Response{Command: "ls"}}
+	r := newTestRace(slow, invalid, valid)
+	r.Valid = func(resp *Response) bool { return resp.Command != "" }
+
+	var winner string
+	r.OnWin = func(name string, elapsed time.Duration) { winner = name }
+
+	resp, err := r.Complete(context.Background(), NewRequest("sys", "q"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Command != "ls" || resp.Provider != "c" {
+		t.Errorf("expected ls from c, got %q from %q", resp.Command, resp.Provider)
+	}
+	if winner != "c" {
+		t.Errorf("OnWin: got %q, want c", winner)
+	}
+
+	select {
+	case <-slow.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("slow provider was not cancelled")
+	}
+}
+
+func TestRaceReturnsInvalidWhenNoneValid(t *testing.T) {
+	failing := &fakeProvider{err: &Error{Kind: ErrServer}}
+	invalid := &fakeProvider{resp: &Response{Text: "I am not sure"}}
+	r := newTestRace(failing, invalid)
+	r.Valid = func(resp *Response) bool { return resp.Command != "" }
+	r.OnWin = func(name string, elapsed time.Duration) { t.Errorf("unexpected winner %s", name) }
+
+	resp, err := r.Complete(context.Background(), NewRequest("sys", "q"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "I am not sure" || resp.Provider != "b" {
+		t.Errorf("expected the invalid response from b, got %+v", resp)
+	}
+}
+
+func TestRaceAllFail(t *testing.T) {
+	r := newTestRace(&fakeProvider{err: &Error{Kind: ErrServer}}, &fakeProvider{err: &Error{Kind: ErrAuth}})
+
+	if _, err := r.Complete(context.Background(), NewRequest("sys", "q")); err == nil {
+		t.Fatal("expected error when every provider fails")
+	}
+}
+
+func TestNewProviderStrategy(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers = []string{"ollama", "llamacpp"}
+
+	cfg.Strategy = "race"
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewProvider error: %v", err)
+	}
+	r, ok := provider.(*Race)
+	if !ok {
+		t.Fatalf("expected *Race, got %T", provider)
+	}
+	if names := r.Names(); len(names) != 2 || names[0] != "ollama" || names[1] != "llamacpp" {
+		t.Errorf("unexpected race providers: %v", names)
+	}
+
+	cfg.Strategy = "bogus"
+	if _, err := NewProvider(cfg); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
+
+func TestRaceReportsUsageOfEveryParticipant(t *testing.T) {
+	r := newTestRace(
+		&fakeProvider{resp: &Response{Text: "maybe", Usage: Usage{InputTokens: 10, OutputTokens: 5}}},
+		&fakeProvider{resp: &Response{Text: "perhaps", Usage: Usage{InputTokens: 20, OutputTokens: 7}}},
+		&fakeProvider{err: &Error{Kind: ErrServer}},
+	)
+	r.Valid = func(resp *Response) bool { return resp.Command != "" }
+	reported := map[string]Usage{}
+	r.OnUsage = func(name string, usage Usage) { reported[name] = usage }
+
+	resp, err := r.Complete(context.Background(), NewRequest("sys", "q"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := reported[resp.Provider]; ok || len(reported) != 2 {
+		t.Errorf("expected every participant but %s to be reported, got %v", resp.Provider, reported)
+	}
+	if reported["c"] != (Usage{}) {
+		t.Errorf("expected no tokens for the failed provider, got %+v", reported["c"])
+	}
+	total := resp.Usage.InputTokens
+	for _, u := range reported {
+		total += u.InputTokens
+	}
+	if total != 30 {
+		t.Errorf("input tokens across the race: got %d, want 30", total)
+	}
+}
@@ -0,0 +1,190 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/swibrow/how/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette is a file of recorded provider exchanges. Requests are matched
+// on their conversation turns only; the system prompt varies with the
+// machine and memory, so it is not recorded.
+type Cassette struct {
+	Interactions []CassetteInteraction `yaml:"interactions"`
+}
+
+// CassetteInteraction is one recorded request and its response.
+type CassetteInteraction struct {
+	Messages []CassetteMessage `yaml:"messages"`
+	Response CassetteResponse  `yaml:"response"`
+}
+
+type CassetteMessage struct {
+	Role    Role   `yaml:"role"`
+	Content string `yaml:"content"`
+}
+
+type CassetteResponse struct {
+	Command      string        `yaml:"command,omitempty"`
+	Explanation  string        `yaml:"explanation,omitempty"`
+	Alternatives []Alternative `yaml:"alternatives,omitempty"`
+	Warnings     []string      `yaml:"warnings,omitempty"`
+	Text         string        `yaml:"text,omitempty"`
+}
+
+// LoadCassette reads a cassette file. A missing file is an empty cassette.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Cassette{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	var c Cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path.
+func (c *Cassette) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// Find returns the first recorded response for the conversation in req.
+func (c *Cassette) Find(req Request) (*Response, bool) {
+	messages := cassetteMessages(req)
+	for _, ix := range c.Interactions {
+		if slices.Equal(ix.Messages, messages) {
+			r := ix.Response
+			return &Response{
+				Command:      r.Command,
+				Explanation:  r.Explanation,
+				Alternatives: r.Alternatives,
+				Warnings:     r.Warnings,
+				Text:         r.Text,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// Add records a response for the conversation in req.
+func (c *Cassette) Add(req Request, resp *Response) {
+	c.Interactions = append(c.Interactions, CassetteInteraction{
+		Messages: cassetteMessages(req),
+		Response: CassetteResponse{
+			Command:      resp.Command,
+			Explanation:  resp.Explanation,
+			Alternatives: resp.Alternatives,
+			Warnings:     resp.Warnings,
+			Text:         resp.Text,
+		},
+	})
+}
+
+func cassetteMessages(req Request) []CassetteMessage {
+	messages := make([]CassetteMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = CassetteMessage{Role: m.Role, Content: strings.TrimSpace(m.Content)}
+	}
+	return messages
+}
+
+// Replay answers from a cassette without any network access.
+type Replay struct {
+	cassette *Cassette
+}
+
+func NewReplay(cfg config.ReplayConfig) (*Replay, error) {
+	if cfg.Cassette == "" {
+		return nil, fmt.Errorf("replay cassette not set (configure replay.cassette in ~/.config/how/config.yaml)")
+	}
+	if _, err := os.Stat(cfg.Cassette); err != nil {
+		return nil, fmt.Errorf("replay cassette: %w", err)
+	}
+	cassette, err := LoadCassette(cfg.Cassette)
+	if err != nil {
+		return nil, err
+	}
+	return &Replay{cassette: cassette}, nil
+}
+
+func (r *Replay) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, ok := r.cassette.Find(req)
+	if !ok {
+		question := req.Messages[len(req.Messages)-1].Content
+		return nil, &Error{Kind: ErrBadRequest, Provider: "replay", Err: fmt.Errorf("no recorded response for %q", question)}
+	}
+	return resp, nil
+}
+
+// Recorder wraps a provider and appends each successful exchange to a
+// cassette file for later replay.
+type Recorder struct {
+	provider Provider
+	path     string
+	mu       sync.Mutex
+}
+
+// NewRecorder records exchanges with p to the cassette at path, adding to
+// any interactions already in it.
+func NewRecorder(p Provider, path string) *Recorder {
+	return &Recorder{provider: p, path: path}
+}
+
+func (r *Recorder) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := r.provider.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Stream implements StreamingProvider, calling Complete on providers that
+// cannot stream.
+func (r *Recorder) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	sp, ok := r.provider.(StreamingProvider)
+	if !ok {
+		return r.Complete(ctx, req)
+	}
+	resp, err := sp.Stream(ctx, req, onChunk)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) record(req Request, resp *Response) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cassette, err := LoadCassette(r.path)
+	if err != nil {
+		return err
+	}
+	cassette.Add(req, resp)
+	return cassette.Save(r.path)
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/swibrow/how/internal/config"
+)
+
+type stubProvider struct {
+	responses map[string]*Response
+}
+
+func (s stubProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	return s.responses[req.Messages[len(req.Messages)-1].Content], nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.yaml")
+	live := stubProvider{responses: map[string]*Response{
+		"list files":     {Command: "ls -la", Explanation: "List files", Text: `{"command":"ls -la"}`},
+		"include hidden": {Command: "ls -A", Explanation: "Include dotfiles", Warnings: []string{"none"}},
+	}}
+	recorder := NewRecorder(live, path)
+
+	first := NewRequest("system prompt on the recording machine", "list files")
+	if _, err := recorder.Complete(context.Background(), first); err != nil {
+		t.Fatalf("recording first turn: %v", err)
+	}
+	refined := first
+	refined.Messages = append(refined.Messages,
+		Message{Role: RoleAssistant, Content: `{"command":"ls -la"}`},
+		Message{Role: RoleUser, Content: "include hidden"},
+	)
+	if _, err := recorder.Stream(context.Background(), refined, func(string) {}); err != nil {
+		t.Fatalf("recording refinement: %v", err)
+	}
+
+	replay, err := NewReplay(config.ReplayConfig{Cassette: path})
+	if err != nil {
+		t.Fatalf("NewReplay error: %v", err)
+	}
+
+	// The system prompt differs on the replaying machine; only the
+	// conversation has to match.
+	resp, err := replay.Complete(context.Background(), NewRequest("another system prompt", "list files"))
+	if err != nil {
+		t.Fatalf("replaying first turn: %v", err)
+	}
+	if resp.Command != "ls -la" || resp.Text != `{"command":"ls -la"}` {
+		t.Errorf("unexpected replayed response: %+v", resp)
+	}
+
+	refined.System = "another system prompt"
+	resp, err = replay.Complete(context.Background(), refined)
+	if err != nil {
+		t.Fatalf("replaying refinement: %v", err)
+	}
+	if resp.Command != "ls -A" || len(resp.Warnings) != 1 {
+		t.Errorf("unexpected replayed refinement: %+v", resp)
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.yaml")
+	if err := (&Cassette{}).Save(path); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	replay, err := NewReplay(config.ReplayConfig{Cassette: path})
+	if err != nil {
+		t.Fatalf("NewReplay error: %v", err)
+	}
+
+	_, err = replay.Complete(context.Background(), NewRequest("system", "unknown question"))
+	if KindOf(err) != ErrBadRequest {
+		t.Errorf("expected bad request error, got %v", err)
+	}
+}
+
+func TestNewReplayMissingCassette(t *testing.T) {
+	if _, err := NewReplay(config.ReplayConfig{Cassette: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("expected error for a missing cassette")
+	}
+	if _, err := NewReplay(config.ReplayConfig{}); err == nil {
+		t.Error("expected error when no cassette is configured")
+	}
+}
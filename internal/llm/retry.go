@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how transient provider errors are retried.
+type RetryPolicy struct {
+	// Timeout bounds each attempt. Zero means no timeout.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on each
+	// subsequent retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Retrying wraps a provider with per-attempt timeouts and retries rate
+// limits, server errors and network failures with exponential backoff and
+// jitter, honouring Retry-After. A Retry-After longer than MaxDelay is not
+// waited out; the error is returned so a fallback chain can move on.
+type Retrying struct {
+	provider Provider
+	policy   RetryPolicy
+
+	// sleep waits for d or until ctx is done. Tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetrying wraps p with the given policy.
+func NewRetrying(p Provider, policy RetryPolicy) *Retrying {
+	return &Retrying{provider: p, policy: policy, sleep: sleepContext}
+}
+
+func (r *Retrying) Complete(ctx context.Context, req Request) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, bool, error) {
+		resp, err := r.provider.Complete(ctx, req)
+		return resp, false, err
+	})
+}
+
+// Stream implements StreamingProvider, calling Complete on backends that
+// cannot stream. A stream that fails after producing output is not retried.
+func (r *Retrying) Stream(ctx context.Context, req Request, onChunk func(string)) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, bool, error) {
+		sp, ok := r.provider.(StreamingProvider)
+		if !ok {
+			resp, err := r.provider.Complete(ctx, req)
+			return resp, false, err
+		}
+		streamed := false
+		resp, err := sp.Stream(ctx, req, func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		})
+		return resp, streamed, err
+	})
+}
+
+func (r *Retrying) do(ctx context.Context, call func(context.Context) (*Response, bool, error)) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, streamed, err := r.attempt(ctx, call)
+		if err == nil || streamed || attempt >= r.policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var perr *Error
+		if !errors.As(err, &perr) || !retryable(perr.Kind) {
+			return nil, err
+		}
+		if r.policy.MaxDelay > 0 && perr.RetryAfter > r.policy.MaxDelay {
+			return nil, err
+		}
+
+		delay := max(backoff(r.policy, attempt), perr.RetryAfter)
+		if err := r.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Retrying) attempt(ctx context.Context, call func(context.Context) (*Response, bool, error)) (*Response, bool, error) {
+	if r.policy.Timeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.policy.Timeout)
+	defer cancel()
+	return call(ctx)
+}
+
+// retryable reports whether an error kind is worth retrying against the
+// same backend. Timeouts are not: the attempt already used its full budget.
+func retryable(kind ErrorKind) bool {
+	return kind == ErrRateLimit || kind == ErrServer || kind == ErrNetwork
+}
+
+// backoff returns the delay before retry number attempt (0-based): the
+// base delay doubled per attempt, capped, with jitter in [d/2, d].
+func backoff(p RetryPolicy, attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d <= 0) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails with errs in order, then succeeds.
+type flakyProvider struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &Response{Command: "ls"}, nil
+}
+
+func newTestRetrying(p Provider, maxRetries int) (*Retrying, *[]time.Duration) {
+	var slept []time.Duration
+	r := NewRetrying(p, RetryPolicy{MaxRetries: maxRetries, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second})
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return r, &slept
+}
+
+func TestRetryingRetriesTransientErrors(t *testing.T) {
+	p := &flakyProvider{errs: []error{&Error{Kind: ErrServer}, &Error{Kind: ErrRateLimit}}}
+	r, slept := newTestRetrying(p, 2)
+
+	resp, err := r.Complete(context.Background(), NewRequest("sys", "q"))
+	if err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if resp.Command != "ls" {
+		t.Errorf("command: got %q", resp.Command)
+	}
+	if p.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", p.calls)
+	}
+	if len(*slept) != 2 {
+		t.Errorf("expected 2 backoff sleeps, got %v", *slept)
+	}
+}
+
+func TestRetryingGivesUpAfterMaxRetries(t *testing.T) {
+	p := &flakyProvider{errs: []error{&Error{Kind: ErrServer}, &Error{Kind: ErrServer}, &Error{Kind: ErrServer}}}
+	r, _ := newTestRetrying(p, 1)
+
+	if _, err := r.Complete(context.Background(), NewRequest("sys", "q")); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if p.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", p.calls)
+	}
+}
+
+func TestRetryingDoesNotRetryPermanentErrors(t *testing.T) {
+	for _, kind := range []ErrorKind{ErrAuth, ErrBadRequest, ErrTimeout} {
+		t.Run(kind.String(), func(t *testing.T) {
+			p := &flakyProvider{errs: []error{&Error{Kind: kind}}}
+			r, _ := newTestRetrying(p, 3)
+
+			if _, err := r.Complete(context.Background(), NewRequest("sys", "q")); err == nil {
+				t.Fatal("expected error")
+			}
+			if p.calls != 1 {
+				t.Errorf("expected 1 call, got %d", p.calls)
+			}
+		})
+	}
+}
+
+func TestRetryingHonoursRetryAfter(t *testing.T) {
+	p := &flakyProvider{errs: []error{&Error{Kind: ErrRateLimit, RetryAfter: 3 * time.Second}}}
+	r, slept := newTestRetrying(p, 2)
+
+	if _, err := r.Complete(context.Background(), NewRequest("sys", "q")); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 3*time.Second {
+		t.Errorf("expected a 3s wait, got %v", *slept)
+	}
+}
+
+func TestRetryingSkipsLongRetryAfter(t *testing.T) {
+	p := &flakyProvider{errs: []error{&Error{Kind: ErrRateLimit, RetryAfter: time.Minute}}}
+	r, slept := newTestRetrying(p, 2)
+
+	if _, err := r.Complete(context.Background(), NewRequest("sys", "q")); err == nil {
+		t.Fatal("expected error when Retry-After exceeds the maximum delay")
+	}
+	if len(*slept) != 0 {
+		t.Errorf("should not wait, got %v", *slept)
+	}
+}
+
+// slowProvider blocks until its context is done.
+type slowProvider struct{}
+
+func (slowProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	<-ctx.Done()
+	return nil, wrapError("slow", ctx.Err())
+}
+
+func TestRetryingTimeout(t *testing.T) {
+	r := NewRetrying(slowProvider{}, RetryPolicy{Timeout: 10 * time.Millisecond})
+
+	_, err := r.Complete(context.Background(), NewRequest("sys", "q"))
+	if KindOf(err) != ErrTimeout {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		got := backoff(p, attempt)
+		if got < want/2 || got > want {
+			t.Errorf("backoff(%d) = %v, want within [%v, %v]", attempt, got, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Response is a provider's answer. Backends with structured output fill the
+// typed fields directly; Text always holds the raw model output so callers
+// can fall back to text parsing when the typed fields are empty. Provider
+// names the backend that answered when a fallback chain is in use, and
+// Cached is set when the response came from the local response cache.
+// Usage is what the request cost; it is not kept in the cache.
+type Response struct {
+	Command      string
+	Explanation  string
+	Alternatives []Alternative
+	Warnings     []string
+	Text         string
+	Provider     string
+	Cached       bool
+	Usage        Usage `json:"-"`
+}
+
+// Usage is the token count a provider reported for one request. Backends
+// that do not report usage leave it zero.
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// Alternative is another command that answers the same question.
+type Alternative struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation"`
+}
+
+// suggestToolName is the tool Anthropic models are forced to call, and the
+// schema name used for OpenAI-style response_format requests.
+const suggestToolName = "suggest_command"
+
+const suggestToolDescription = "Return the shell command that answers the user's question."
+
+var alternativeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"command":     map[string]any{"type": "string"},
+		"explanation": map[string]any{"type": "string"},
+	},
+	"required":             []string{"command", "explanation"},
+	"additionalProperties": false,
+}
+
+// responseProperties describes the structured answer. Every property is
+// required because OpenAI strict mode does not allow optional fields;
+// models return empty arrays when there is nothing to add.
+var responseProperties = map[string]any{
+	"command": map[string]any{
+		"type":        "string",
+		"description": "The shell command, without backticks or code fences",
+	},
+	"explanation": map[string]any{
+		"type":        "string",
+		"description": "Brief one-line explanation of the command",
+	},
+	"alternatives": map[string]any{
+		"type":        "array",
+		"description": "Other good commands for the same task, if any",
+		"items":       alternativeSchema,
+	},
+	"warnings": map[string]any{
+		"type":        "array",
+		"description": "Caveats the user should know before running the command, e.g. that it deletes data",
+		"items":       map[string]any{"type": "string"},
+	},
+}
+
+var responseRequired = []string{"command", "explanation", "alternatives", "warnings"}
+
+func responseSchema() map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"properties":           responseProperties,
+		"required":             responseRequired,
+		"additionalProperties": false,
+	}
+}
+
+type structuredResponse struct {
+	Command      string        `json:"command"`
+	Explanation  string        `json:"explanation"`
+	Alternatives []Alternative `json:"alternatives"`
+	Warnings     []string      `json:"warnings"`
+}
+
+func withUsage(resp *Response, usage Usage) *Response {
+	resp.Usage = usage
+	return resp
+}
+
+// decodeResponse builds a Response from raw model output. JSON matching the
+// response schema populates the typed fields; anything else is returned as
+// Text only, leaving it to the caller's fallback parser.
+func decodeResponse(text string) *Response {
+	resp := &Response{Text: text}
+
+	trimmed := strings.TrimSpace(text)
+	trimmed = strings.TrimPrefix(trimmed, "```json")
+	trimmed = strings.Trim(trimmed, "`\n ")
+	if !strings.HasPrefix(trimmed, "{") {
+		return resp
+	}
+
+	var sr structuredResponse
+	if err := json.Unmarshal([]byte(trimmed), &sr); err != nil {
+		return resp
+	}
+
+	resp.Command = strings.TrimSpace(sr.Command)
+	resp.Explanation = strings.TrimSpace(sr.Explanation)
+	for _, alt := range sr.Alternatives {
+		if cmd := strings.TrimSpace(alt.Command); cmd != "" {
+			resp.Alternatives = append(resp.Alternatives, Alternative{
+				Command:     cmd,
+				Explanation: strings.TrimSpace(alt.Explanation),
+			})
+		}
+	}
+	for _, w := range sr.Warnings {
+		if w = strings.TrimSpace(w); w != "" {
+			resp.Warnings = append(resp.Warnings, w)
+		}
+	}
+	return resp
+}
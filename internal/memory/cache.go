@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const timeFormat = "2006-01-02T15:04:05Z"
+
+// CacheKey identifies a cached response. The system prompt is hashed so
+// any change to it (custom prompt, OS hint, memory context) misses the
+// cache; the question is normalized so trivial differences in case,
+// spacing or a trailing question mark still hit.
+func CacheKey(provider, model, systemPrompt, question string) string {
+	h := sha256.New()
+	for _, part := range []string{provider, model, systemPrompt, normalizeQuestion(question)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizeQuestion(question string) string {
+	q := strings.ToLower(strings.Join(strings.Fields(question), " "))
+	return strings.TrimRight(q, "?!. ")
+}
+
+// CacheGet returns the cached response for key if it is younger than ttl.
+// A ttl of zero or less never expires.
+func (s *Store) CacheGet(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	query := `SELECT response FROM response_cache WHERE key = ?`
+	args := []any{key}
+	if ttl > 0 {
+		query += ` AND created_at >= ?`
+		args = append(args, time.Now().UTC().Add(-ttl).Format(timeFormat))
+	}
+
+	var response string
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&response)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("reading cache: %w", err)
+	}
+	return response, true, nil
+}
+
+// CachePut stores a response under key, replacing any previous entry.
+func (s *Store) CachePut(ctx context.Context, key, response string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO response_cache (key, response, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET response = excluded.response, created_at = excluded.created_at`,
+		key, response, time.Now().UTC().Format(timeFormat),
+	)
+	if err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	return nil
+}
+
+// CachePrune deletes entries older than ttl.
+func (s *Store) CachePrune(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	cutoff := time.Now().UTC().Add(-ttl).Format(timeFormat)
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM response_cache WHERE created_at < ?`, cutoff); err != nil {
+		return fmt.Errorf("pruning cache: %w", err)
+	}
+	return nil
+}
+
+// CacheClear removes every cached response.
+func (s *Store) CacheClear(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM response_cache"); err != nil {
+		return fmt.Errorf("clearing cache: %w", err)
+	}
+	return nil
+}
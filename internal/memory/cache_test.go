@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyNormalizesQuestion(t *testing.T) {
+	a := CacheKey("anthropic", "claude-sonnet-4-6", "prompt", "Undo last  git commit?")
+	b := CacheKey("anthropic", "claude-sonnet-4-6", "prompt", "undo last git commit")
+	if a != b {
+		t.Error("expected normalized questions to share a cache key")
+	}
+}
+
+func TestCacheKeyDistinguishesInputs(t *testing.T) {
+	base := CacheKey("anthropic", "claude-sonnet-4-6", "prompt", "list files")
+	others := []string{
+		CacheKey("openai", "claude-sonnet-4-6", "prompt", "list files"),
+		CacheKey("anthropic", "claude-haiku-4-5", "prompt", "list files"),
+		CacheKey("anthropic", "claude-sonnet-4-6", "other prompt", "list files"),
+		CacheKey("anthropic", "claude-sonnet-4-6", "prompt", "list dirs"),
+	}
+	for i, k := range others {
+		if k == base {
+			t.Errorf("key %d should differ from the base key", i)
+		}
+	}
+}
+
+func TestCachePutAndGet(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := store.CacheGet(ctx, "k", time.Hour); err != nil || ok {
+		t.Fatalf("expected miss on empty cache, got ok=%v err=%v", ok, err)
+	}
+
+	if err := store.CachePut(ctx, "k", "first"); err != nil {
+		t.Fatalf("CachePut error: %v", err)
+	}
+	if err := store.CachePut(ctx, "k", "second"); err != nil {
+		t.Fatalf("CachePut error: %v", err)
+	}
+
+	got, ok, err := store.CacheGet(ctx, "k", time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("expected hit, got ok=%v err=%v", ok, err)
+	}
+	if got != "second" {
+		t.Errorf("response: got %q, want %q", got, "second")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	old := time.Now().UTC().Add(-2 * time.Hour).Format(timeFormat)
+	if _, err := store.db.ExecContext(ctx,
+		`INSERT INTO response_cache (key, response, created_at) VALUES (?, ?, ?)`, "old", "stale", old); err != nil {
+		t.Fatalf("inserting old entry: %v", err)
+	}
+
+	if _, ok, _ := store.CacheGet(ctx, "old", time.Hour); ok {
+		t.Error("expected entry older than the TTL to miss")
+	}
+	if _, ok, _ := store.CacheGet(ctx, "old", 0); !ok {
+		t.Error("expected a zero TTL to never expire")
+	}
+
+	if err := store.CachePrune(ctx, time.Hour); err != nil {
+		t.Fatalf("CachePrune error: %v", err)
+	}
+	if _, ok, _ := store.CacheGet(ctx, "old", 0); ok {
+		t.Error("expected pruned entry to be gone")
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	_ = store.CachePut(ctx, "k", "v")
+	if err := store.CacheClear(ctx); err != nil {
+		t.Fatalf("CacheClear error: %v", err)
+	}
+	if _, ok, _ := store.CacheGet(ctx, "k", 0); ok {
+		t.Error("expected cache to be empty after clear")
+	}
+}
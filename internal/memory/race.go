@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RaceRow is how often one provider took part in and won a race.
+type RaceRow struct {
+	Provider string
+	Races    int64
+	Wins     int64
+}
+
+// RecordRace stores the outcome of one race: a row per participant, marking
+// the winner.
+func (s *Store) RecordRace(ctx context.Context, winner string, participants []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("recording race: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	now := time.Now().UTC().Format(timeFormat)
+	for _, p := range participants {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO race_results (provider, won, created_at) VALUES (?, ?, ?)`,
+			p, p == winner, now,
+		)
+		if err != nil {
+			return fmt.Errorf("recording race: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RaceStats returns each provider's races and wins since the given time,
+// most wins first.
+func (s *Store) RaceStats(ctx context.Context, since time.Time) ([]RaceRow, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT provider, COUNT(*), SUM(won)
+		 FROM race_results
+		 WHERE created_at >= ?
+		 GROUP BY provider
+		 ORDER BY SUM(won) DESC, provider`,
+		since.UTC().Format(timeFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading race stats: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var stats []RaceRow
+	for rows.Next() {
+		var r RaceRow
+		if err := rows.Scan(&r.Provider, &r.Races, &r.Wins); err != nil {
+			return nil, fmt.Errorf("scanning race stats: %w", err)
+		}
+		stats = append(stats, r)
+	}
+	return stats, rows.Err()
+}
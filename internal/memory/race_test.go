@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRaceStats(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	participants := []string{"anthropic", "openai"}
+	for _, winner := range []string{"anthropic", "openai", "anthropic"} {
+		if err := store.RecordRace(ctx, winner, participants); err != nil {
+			t.Fatalf("RecordRace error: %v", err)
+		}
+	}
+
+	stats, err := store.RaceStats(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("RaceStats error: %v", err)
+	}
+	want := []RaceRow{
+		{Provider: "anthropic", Races: 3, Wins: 2},
+		{Provider: "openai", Races: 3, Wins: 1},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), stats)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
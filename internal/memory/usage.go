@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UsageRow is the token usage of one provider and model on one day.
+type UsageRow struct {
+	// Day is the local date, formatted as 2006-01-02.
+	Day          string
+	Provider     string
+	Model        string
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// RecordUsage stores the tokens used by one provider request.
+func (s *Store) RecordUsage(ctx context.Context, provider, model string, inputTokens, outputTokens int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO usage (provider, model, input_tokens, output_tokens, created_at) VALUES (?, ?, ?, ?, ?)`,
+		provider, model, inputTokens, outputTokens, time.Now().UTC().Format(timeFormat),
+	)
+	if err != nil {
+		return fmt.Errorf("recording usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns usage since the given time, summed per day, provider and
+// model, newest day first.
+func (s *Store) Usage(ctx context.Context, since time.Time) ([]UsageRow, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT date(created_at, 'localtime') AS day, provider, model,
+		        COUNT(*), SUM(input_tokens), SUM(output_tokens)
+		 FROM usage
+		 WHERE created_at >= ?
+		 GROUP BY day, provider, model
+		 ORDER BY day DESC, provider, model`,
+		since.UTC().Format(timeFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading usage: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var usage []UsageRow
+	for rows.Next() {
+		var r UsageRow
+		if err := rows.Scan(&r.Day, &r.Provider, &r.Model, &r.Requests, &r.InputTokens, &r.OutputTokens); err != nil {
+			return nil, fmt.Errorf("scanning usage: %w", err)
+		}
+		usage = append(usage, r)
+	}
+	return usage, rows.Err()
+}
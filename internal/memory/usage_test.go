@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	records := []struct {
+		provider, model string
+		in, out         int64
+	}{
+		{"anthropic", "claude-sonnet-4-6", 100, 20},
+		{"anthropic", "claude-sonnet-4-6", 150, 30},
+		{"ollama", "llama3", 80, 10},
+	}
+	for _, r := range records {
+		if err := store.RecordUsage(ctx, r.provider, r.model, r.in, r.out); err != nil {
+			t.Fatalf("RecordUsage error: %v", err)
+		}
+	}
+
+	// An old record outside the window.
+	old := time.Now().UTC().Add(-48 * time.Hour).Format(timeFormat)
+	if _, err := store.db.Exec(
+		`INSERT INTO usage (provider, model, input_tokens, output_tokens, created_at) VALUES ('openai', 'gpt-4o', 1, 1, ?)`, old,
+	); err != nil {
+		t.Fatalf("inserting old usage: %v", err)
+	}
+
+	rows, err := store.Usage(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Usage error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d: %+v", len(rows), rows)
+	}
+
+	got := rows[0]
+	if got.Provider != "anthropic" || got.Requests != 2 || got.InputTokens != 250 || got.OutputTokens != 50 {
+		t.Errorf("unexpected anthropic row: %+v", got)
+	}
+	if got.Day != time.Now().Format("2006-01-02") {
+		t.Errorf("day: got %q", got.Day)
+	}
+	if rows[1].Provider != "ollama" || rows[1].Requests != 1 {
+		t.Errorf("unexpected ollama row: %+v", rows[1])
+	}
+}
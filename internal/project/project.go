@@ -0,0 +1,262 @@
+// Package project detects the build tooling of the project around the
+// current directory, so suggestions can use its real commands.
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxItems caps the scripts, targets or services listed per marker.
+const maxItems = 20
+
+// Marker is one project file found, such as a Makefile and its targets.
+type Marker struct {
+	// Path is the file path relative to the directory searched from.
+	Path string
+	// Detail is a short description, e.g. the Go module path.
+	Detail string
+	// Label names Items, e.g. "targets" or "scripts".
+	Label string
+	Items []string
+	// More counts items left out beyond maxItems.
+	More int
+}
+
+// detector reads one kind of project file. It returns false if the file
+// is not usable.
+type detector struct {
+	names []string
+	read  func(path string) (Marker, bool)
+}
+
+var detectors = []detector{
+	{[]string{"go.mod"}, readGoMod},
+	{[]string{"package.json"}, readPackageJSON},
+	{[]string{"Makefile", "makefile", "GNUmakefile"}, readMakefile},
+	{[]string{"Cargo.toml"}, readCargo},
+	{[]string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}, readCompose},
+	{[]string{"justfile", "Justfile", ".justfile"}, readJustfile},
+}
+
+// Detect looks for project files in dir and its parents, stopping at the
+// repository root (the first directory containing .git) or the filesystem
+// root. For each kind of file the nearest one wins; markers are returned
+// nearest first.
+func Detect(dir string) []Marker {
+	var markers []Marker
+	found := make([]bool, len(detectors))
+	for d := dir; ; d = filepath.Dir(d) {
+		for i, det := range detectors {
+			if found[i] {
+				continue
+			}
+			for _, name := range det.names {
+				path := filepath.Join(d, name)
+				if !isFile(path) {
+					continue
+				}
+				if m, ok := det.read(path); ok {
+					if rel, err := filepath.Rel(dir, path); err == nil {
+						m.Path = rel
+					}
+					markers = append(markers, m)
+					found[i] = true
+				}
+				break
+			}
+		}
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil || filepath.Dir(d) == d {
+			break
+		}
+	}
+	return markers
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// withItems sets the marker's items, keeping at most maxItems.
+func (m Marker) withItems(label string, items []string) Marker {
+	m.Label = label
+	if len(items) > maxItems {
+		m.More = len(items) - maxItems
+		items = items[:maxItems]
+	}
+	m.Items = items
+	return m
+}
+
+func readGoMod(path string) (Marker, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Marker{}, false
+	}
+	var module, version string
+	for line := range strings.SplitSeq(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			module = fields[1]
+		}
+		if len(fields) == 2 && fields[0] == "go" {
+			version = fields[1]
+		}
+	}
+	detail := "module " + module
+	if version != "" {
+		detail += ", go " + version
+	}
+	return Marker{Detail: detail}, true
+}
+
+// lockfiles identify the Node package manager in use.
+var lockfiles = []struct{ file, manager string }{
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"bun.lockb", "bun"},
+	{"bun.lock", "bun"},
+	{"package-lock.json", "npm"},
+}
+
+func readPackageJSON(path string) (Marker, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Marker{}, false
+	}
+	var pkg struct {
+		Name           string            `json:"name"`
+		PackageManager string            `json:"packageManager"`
+		Scripts        map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return Marker{}, false
+	}
+
+	manager, _, _ := strings.Cut(pkg.PackageManager, "@")
+	for _, l := range lockfiles {
+		if manager != "" {
+			break
+		}
+		if isFile(filepath.Join(filepath.Dir(path), l.file)) {
+			manager = l.manager
+		}
+	}
+
+	var details []string
+	if pkg.Name != "" {
+		details = append(details, pkg.Name)
+	}
+	if manager != "" {
+		details = append(details, "uses "+manager)
+	}
+	scripts := make([]string, 0, len(pkg.Scripts))
+	for name := range pkg.Scripts {
+		scripts = append(scripts, name)
+	}
+	slices.Sort(scripts)
+	return Marker{Detail: strings.Join(details, ", ")}.withItems("scripts", scripts), true
+}
+
+// makeTargetRe matches a rule line, excluding variable assignments.
+var makeTargetRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*)\s*:([^=]|$)`)
+
+func readMakefile(path string) (Marker, bool) {
+	targets, err := matchLines(path, makeTargetRe)
+	if err != nil {
+		return Marker{}, false
+	}
+	return Marker{}.withItems("targets", targets), true
+}
+
+// justRecipeRe matches a recipe header, with optional parameters, but not
+// an assignment (:=).
+var justRecipeRe = regexp.MustCompile(`^@?([A-Za-z0-9_-]+)(\s+[^:=]*)?:([^=]|$)`)
+
+func readJustfile(path string) (Marker, bool) {
+	recipes, err := matchLines(path, justRecipeRe)
+	if err != nil {
+		return Marker{}, false
+	}
+	return Marker{}.withItems("recipes", recipes), true
+}
+
+// matchLines returns the first group of re for each matching line of the
+// file, without duplicates.
+func matchLines(path string, re *regexp.Regexp) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if m := re.FindStringSubmatch(scanner.Text()); m != nil && !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names, scanner.Err()
+}
+
+func readCargo(path string) (Marker, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Marker{}, false
+	}
+	defer f.Close() //nolint:errcheck
+
+	var section, name string
+	var workspace bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[]")
+			workspace = workspace || section == "workspace"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && section == "package" && strings.TrimSpace(key) == "name" {
+			name = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	var details []string
+	if name != "" {
+		details = append(details, "crate "+name)
+	}
+	if workspace {
+		details = append(details, "workspace")
+	}
+	return Marker{Detail: strings.Join(details, ", ")}, true
+}
+
+func readCompose(path string) (Marker, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Marker{}, false
+	}
+	var compose struct {
+		Services yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return Marker{}, false
+	}
+	// Services are read from the mapping node to keep the file's order.
+	var services []string
+	content := compose.Services.Content
+	for i := 0; i+1 < len(content); i += 2 {
+		services = append(services, content[i].Value)
+	}
+	return Marker{}.withItems("services", services), true
+}
@@ -0,0 +1,82 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.25.0\n")
+	writeFile(t, filepath.Join(root, "Makefile"), ".PHONY: build test\nVERSION := 1.0\n\nbuild: deps\n\tgo build ./...\n\ntest:\n\tgo test ./...\n")
+	writeFile(t, filepath.Join(root, "compose.yaml"), "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	writeFile(t, filepath.Join(root, "web", "package.json"), `{"name":"web","scripts":{"test":"vitest","dev":"vite"}}`)
+	writeFile(t, filepath.Join(root, "web", "pnpm-lock.yaml"), "")
+	// Outside the repository, so never read.
+	writeFile(t, filepath.Join(filepath.Dir(root), "justfile"), "default:\n")
+
+	markers := Detect(filepath.Join(root, "web"))
+
+	// Nearest first.
+	want := []Marker{
+		{Path: "package.json", Detail: "web, uses pnpm", Label: "scripts", Items: []string{"dev", "test"}},
+		{Path: "../go.mod", Detail: "module example.com/app, go 1.25.0"},
+		{Path: "../Makefile", Label: "targets", Items: []string{"build", "test"}},
+		{Path: "../compose.yaml", Label: "services", Items: []string{"web", "db"}},
+	}
+	if len(markers) != len(want) {
+		t.Fatalf("got %d markers, want %d: %+v", len(markers), len(want), markers)
+	}
+	for i, w := range want {
+		m := markers[i]
+		if m.Path != w.Path || m.Detail != w.Detail || m.Label != w.Label || !slices.Equal(m.Items, w.Items) {
+			t.Errorf("marker %d: got %+v, want %+v", i, m, w)
+		}
+	}
+}
+
+func TestDetectJustfileAndCargo(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "justfile"), "set shell := [\"bash\", \"-c\"]\nalias b := build\n\nbuild:\n    cargo build\n\ntest *args: build\n    cargo test {{args}}\n")
+	writeFile(t, filepath.Join(dir, "Cargo.toml"), "[package]\nname = \"tool\"\nversion = \"0.1.0\"\n\n[dependencies]\nname = \"ignored\"\n")
+
+	markers := Detect(dir)
+	if len(markers) != 2 {
+		t.Fatalf("got %+v", markers)
+	}
+	if markers[0].Path != "Cargo.toml" || markers[0].Detail != "crate tool" {
+		t.Errorf("cargo marker: %+v", markers[0])
+	}
+	if markers[1].Path != "justfile" || !slices.Equal(markers[1].Items, []string{"build", "test"}) {
+		t.Errorf("justfile marker: %+v", markers[1])
+	}
+}
+
+func TestDetectCapsItems(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	for i := range maxItems + 5 {
+		fmt.Fprintf(&b, "target%d:\n", i)
+	}
+	writeFile(t, filepath.Join(dir, "Makefile"), b.String())
+
+	markers := Detect(dir)
+	if len(markers) != 1 || len(markers[0].Items) != maxItems || markers[0].More != 5 {
+		t.Errorf("expected %d targets and 5 more, got %+v", maxItems, markers)
+	}
+}
@@ -5,12 +5,19 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/swibrow/how/internal/cloud"
+	"github.com/swibrow/how/internal/env"
+	"github.com/swibrow/how/internal/git"
+	"github.com/swibrow/how/internal/input"
 	"github.com/swibrow/how/internal/memory"
+	"github.com/swibrow/how/internal/project"
 )
 
 const baseSystemPrompt = `You are a terminal command expert. The user will ask how to do something on the command line. Respond with the most appropriate command and a brief explanation.
 
-You MUST respond in exactly this format:
+When given a tool or JSON schema for your answer, put only the command itself in the command field, without a "COMMAND:" prefix, and the explanation in the explanation field.
+
+If answering in plain text, use exactly this format:
 
 COMMAND: <the command>
 EXPLANATION: <brief one-line explanation>
@@ -20,7 +27,7 @@ Rules:
 - Prefer standard Unix tools (coreutils, grep, sed, awk, jq, curl, etc.)
 - If multiple commands are needed, chain them with pipes or && as appropriate
 - Do not wrap the command in backticks or code blocks
-- Do not include any text outside the COMMAND/EXPLANATION format
+- In plain text, do not include any text outside the COMMAND/EXPLANATION format
 - If the question is ambiguous, pick the most common interpretation
 - ACCURACY IS CRITICAL: Only suggest commands, flags, and syntax you are confident actually exist. Never invent flags, query languages, subcommands, or API syntax. If unsure about exact syntax, prefer a simpler command you know is correct over a complex one that might be wrong.
 - Prefer well-known CLI tools with their documented flags. For GitHub operations, always use the gh CLI (e.g. gh run list, gh api). For Kubernetes use kubectl. For Docker use docker/docker-compose. Do not guess at undocumented features.
@@ -35,18 +42,124 @@ Examples of interactive selection:
 - Opening a file: find . -type f | fzf | xargs open
 - Checking out a PR: gh pr list | fzf | awk '{print $1}' | xargs gh pr checkout`
 
-// SystemPrompt returns the system prompt with OS-specific context appended.
-// If customPrompt is non-empty, it replaces the default base prompt.
-func SystemPrompt(customPrompt string) string {
+// SystemPrompt returns the system prompt with OS-specific context appended,
+// followed by any context sections such as FormatEnvironment. If
+// customPrompt is non-empty, it replaces the default base prompt.
+func SystemPrompt(customPrompt string, sections ...string) string {
 	base := baseSystemPrompt
 	if customPrompt != "" {
 		base = customPrompt
 	}
-	osHint := osContext()
-	if osHint == "" {
-		return base
+	if osHint := osContext(); osHint != "" {
+		base += "\n- " + osHint
+	}
+	for _, s := range sections {
+		if s != "" {
+			base += "\n\n" + s
+		}
+	}
+	return base
+}
+
+const reviewSystemPrompt = `You review shell commands suggested by another assistant before the user runs them. Check that:
+- every flag, subcommand, option and syntax used actually exists in the tool as commonly installed
+- the command works on the user's system and shell
+- the command does what the user asked
+- the command is not needlessly dangerous (deleting data, requiring root, changing remote systems) for the task
+
+Give your verdict in place of the command: PASS if the command is correct, FAIL if any check fails. Put a one-line critique in the explanation and each concrete problem as a separate warning.
+
+If answering in plain text, use exactly this format:
+
+COMMAND: PASS or FAIL
+EXPLANATION: <one-line critique>`
+
+// ReviewPrompt returns the system prompt for critiquing a suggested
+// command, with the same OS context and sections as SystemPrompt.
+func ReviewPrompt(sections ...string) string {
+	p := reviewSystemPrompt
+	if osHint := osContext(); osHint != "" {
+		p += "\n\n" + osHint
+	}
+	for _, s := range sections {
+		if s != "" {
+			p += "\n\n" + s
+		}
+	}
+	return p
+}
+
+// FormatReview formats a suggested command as the user message of a review
+// request.
+func FormatReview(question, command, explanation string) string {
+	return fmt.Sprintf("Question: %s\nCommand: %s\nExplanation: %s", question, command, explanation)
+}
+
+// FormatReviewFeedback tells the model why its previous command was
+// rejected so it can suggest a corrected one.
+func FormatReviewFeedback(critique string, issues []string) string {
+	var b strings.Builder
+	b.WriteString("A reviewer rejected that command")
+	if critique != "" {
+		b.WriteString(": " + critique)
+	}
+	b.WriteString("\n")
+	for _, issue := range issues {
+		b.WriteString("- " + issue + "\n")
+	}
+	b.WriteString("Suggest a corrected command.")
+	return b.String()
+}
+
+// FormatInput puts input piped into how before the question, as the user
+// message. The suggested command does not receive the input, so the model
+// is told to write one the user can pipe the same data into.
+func FormatInput(question string, e *input.Excerpt) string {
+	if e == nil {
+		return question
+	}
+
+	var b strings.Builder
+	if e.Binary {
+		fmt.Fprintf(&b, "I piped %d bytes of binary data into this question; it is not shown.", e.Size)
+	} else {
+		var notes []string
+		if e.Omitted > 0 {
+			notes = append(notes, fmt.Sprintf("%d bytes left out of the middle", e.Omitted))
+		}
+		if e.Partial {
+			notes = append(notes, "more input after this was not read")
+		}
+		if e.Redacted > 0 {
+			notes = append(notes, fmt.Sprintf("%d secrets replaced with [REDACTED]", e.Redacted))
+		}
+		b.WriteString("I piped this input into the question")
+		if len(notes) > 0 {
+			b.WriteString(" (" + strings.Join(notes, ", ") + ")")
+		}
+		fence := "```"
+		for strings.Contains(e.Text, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, ":\n%s\n%s\n%s", fence, strings.TrimRight(e.Text, "\n"), fence)
 	}
-	return base + "\n- " + osHint
+	b.WriteString("\nIf the answer is a command that processes this input, make it read from stdin so I can pipe the same input into it, unless I name a file.")
+	b.WriteString("\n\n" + question)
+	return b.String()
+}
+
+// FormatRepair asks the model to restate an answer that could not be
+// parsed. It follows the unparseable reply as the next user turn.
+func FormatRepair() string {
+	return "Your previous reply could not be read because it did not contain a command in the required format. " +
+		"Reply again with only these two lines and no other text:\n\n" +
+		"COMMAND: <the command>\nEXPLANATION: <brief one-line explanation>"
+}
+
+// FormatAnswer restates a chosen command as the assistant's turn, so a
+// refinement builds on the command the user saw rather than the raw reply.
+func FormatAnswer(command, explanation string) string {
+	return "COMMAND: " + command + "\nEXPLANATION: " + explanation
 }
 
 // FormatMemoryContext formats past interactions as context for the LLM prompt.
@@ -68,6 +181,145 @@ func FormatMemoryContext(interactions []memory.Interaction) string {
 	return b.String()
 }
 
+// FormatEnvironment describes the user's machine and installed tools so
+// the model only suggests commands that will run.
+func FormatEnvironment(e env.Environment) string {
+	var b strings.Builder
+	b.WriteString("The user's environment:\n")
+	if e.Distro != "" {
+		fmt.Fprintf(&b, "- OS: %s\n", e.Distro)
+	}
+	if e.Shell != "" {
+		fmt.Fprintf(&b, "- Shell: %s\n", e.Shell)
+	}
+	if e.PackageManager != "" {
+		fmt.Fprintf(&b, "- Package manager: %s\n", e.PackageManager)
+	}
+	if len(e.Installed) > 0 {
+		fmt.Fprintf(&b, "- Installed: %s\n", strings.Join(e.Installed, ", "))
+	}
+	if len(e.Missing) > 0 {
+		fmt.Fprintf(&b, "- Not installed: %s\n", strings.Join(e.Missing, ", "))
+		b.WriteString("Do not use tools that are not installed, including fzf for interactive selection, unless the user asks for them; use an installed or standard alternative instead.")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// maxProjectContext bounds the project summary, in bytes, so a large
+// monorepo cannot crowd out the question.
+const maxProjectContext = 1500
+
+// FormatProject summarizes the project files found around the working
+// directory so the model uses the project's own tooling. Markers that would
+// take the summary past maxProjectContext are left out.
+func FormatProject(markers []project.Marker) string {
+	if len(markers) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("The user is working in a project. Prefer its own tooling (make targets, package scripts, compose services) when relevant:")
+	for _, m := range markers {
+		line := "\n- " + m.Path
+		if m.Detail != "" {
+			line += ": " + m.Detail
+		}
+		if len(m.Items) > 0 {
+			sep := ": "
+			if m.Detail != "" {
+				sep = "; "
+			}
+			line += sep + m.Label + " " + strings.Join(m.Items, ", ")
+			if m.More > 0 {
+				line += fmt.Sprintf(" (and %d more)", m.More)
+			}
+		}
+		if b.Len()+len(line) > maxProjectContext {
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// FormatGit describes the repository the user is in, so git answers can
+// account for its branch, unpushed commits and any operation in progress.
+func FormatGit(r *git.Repo) string {
+	if r == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("The current directory is in a git repository:")
+	switch {
+	case r.Branch != "":
+		fmt.Fprintf(&b, "\n- Branch: %s", r.Branch)
+	case r.Head != "":
+		fmt.Fprintf(&b, "\n- HEAD is detached at %s", r.Head)
+	}
+	switch {
+	case r.Head == "":
+		b.WriteString("\n- There are no commits yet")
+	case r.Upstream == "":
+		b.WriteString("\n- The branch has no upstream, so its commits may not have been pushed")
+	case r.UpstreamGone:
+		fmt.Fprintf(&b, "\n- Upstream: %s, which does not exist (not pushed yet or deleted on the remote), so whether the last commit has been pushed is unknown", r.Upstream)
+	default:
+		fmt.Fprintf(&b, "\n- Upstream: %s, %d commits ahead and %d behind", r.Upstream, r.Ahead, r.Behind)
+		if r.Ahead == 0 {
+			b.WriteString(" (the last commit has been pushed)")
+		}
+	}
+	if r.Dirty() {
+		fmt.Fprintf(&b, "\n- Working tree: %d staged, %d unstaged, %d untracked, %d conflicted files",
+			r.Staged, r.Unstaged, r.Untracked, r.Conflicted)
+	} else {
+		b.WriteString("\n- Working tree: clean")
+	}
+	if r.Operation != "" {
+		fmt.Fprintf(&b, "\n- A %s is in progress", r.Operation)
+	}
+	for _, remote := range r.Remotes {
+		fmt.Fprintf(&b, "\n- Remote %s: %s", remote.Name, remote.URL)
+	}
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(&b, "\n- Recent tags: %s", strings.Join(r.Tags, ", "))
+	}
+	return b.String()
+}
+
+// FormatCloud describes the kubectl context and AWS profile that commands
+// will act on. Either may be nil.
+func FormatCloud(k *cloud.Kube, a *cloud.AWS) string {
+	if k == nil && a == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Commands act on this cluster and cloud account by default:")
+	if k != nil {
+		fmt.Fprintf(&b, "\n- kubectl context %s", k.Context)
+		if k.Cluster != "" && k.Cluster != k.Context {
+			fmt.Fprintf(&b, " (cluster %s)", k.Cluster)
+		}
+		fmt.Fprintf(&b, ", namespace %s", k.Namespace)
+		if k.Production() {
+			b.WriteString(". This looks like production")
+		}
+	}
+	if a != nil {
+		fmt.Fprintf(&b, "\n- AWS profile %s", a.Profile)
+		if a.Region != "" {
+			fmt.Fprintf(&b, ", region %s", a.Region)
+		}
+		if a.Production() {
+			b.WriteString(". This looks like production")
+		}
+	}
+	b.WriteString("\nIf the user names a different cluster, namespace, profile or region, pass it explicitly with --context/--namespace or --profile/--region. Avoid destructive commands against production unless the user clearly asks for them.")
+	return b.String()
+}
+
 func osContext() string {
 	switch runtime.GOOS {
 	case "darwin":
@@ -4,7 +4,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/swibrow/how/internal/cloud"
+	"github.com/swibrow/how/internal/env"
+	"github.com/swibrow/how/internal/git"
+	"github.com/swibrow/how/internal/input"
 	"github.com/swibrow/how/internal/memory"
+	"github.com/swibrow/how/internal/project"
 )
 
 func TestSystemPromptNotEmpty(t *testing.T) {
@@ -23,6 +28,16 @@ func TestSystemPromptContainsFormat(t *testing.T) {
 	}
 }
 
+func TestSystemPromptTextFormatIsConditional(t *testing.T) {
+	p := SystemPrompt("")
+	if strings.Contains(p, "MUST respond in exactly this format") {
+		t.Error("the plain-text format should not be required when a tool or schema is used")
+	}
+	if !strings.Contains(p, "If answering in plain text") {
+		t.Error("SystemPrompt should describe the plain-text format")
+	}
+}
+
 func TestSystemPromptContainsOSContext(t *testing.T) {
 	p := SystemPrompt("")
 	if !strings.Contains(p, "user is on") {
@@ -54,6 +69,150 @@ func TestSystemPromptEmptyUsesDefault(t *testing.T) {
 	}
 }
 
+func TestSystemPromptSections(t *testing.T) {
+	p := SystemPrompt("", "", "Section one")
+	if !strings.HasSuffix(p, "\n\nSection one") {
+		t.Errorf("expected the section at the end, got %q", p[len(p)-40:])
+	}
+	if strings.Contains(p, "\n\n\n") {
+		t.Error("empty sections should be skipped")
+	}
+}
+
+func TestFormatEnvironment(t *testing.T) {
+	got := FormatEnvironment(env.Environment{
+		Distro:         "Ubuntu 24.04 LTS",
+		Shell:          "zsh",
+		PackageManager: "apt",
+		Installed:      []string{"git", "jq"},
+		Missing:        []string{"fd", "fzf"},
+	})
+	for _, want := range []string{"OS: Ubuntu 24.04 LTS", "Shell: zsh", "Package manager: apt", "Installed: git, jq", "Not installed: fd, fzf"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	if got := FormatEnvironment(env.Environment{Installed: []string{"git"}}); strings.Contains(got, "Not installed") {
+		t.Errorf("no missing tools should leave out the warning, got:\n%s", got)
+	}
+}
+
+func TestFormatProject(t *testing.T) {
+	if got := FormatProject(nil); got != "" {
+		t.Errorf("expected empty string without markers, got %q", got)
+	}
+
+	got := FormatProject([]project.Marker{
+		{Path: "go.mod", Detail: "module example.com/app"},
+		{Path: "package.json", Detail: "web", Label: "scripts", Items: []string{"dev", "test"}, More: 3},
+		{Path: "../Makefile", Label: "targets", Items: []string{"build"}},
+	})
+	for _, want := range []string{
+		"- go.mod: module example.com/app",
+		"- package.json: web; scripts dev, test (and 3 more)",
+		"- ../Makefile: targets build",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatProjectBounded(t *testing.T) {
+	var markers []project.Marker
+	for range 100 {
+		markers = append(markers, project.Marker{Path: "Makefile", Label: "targets", Items: []string{"a-fairly-long-target-name", "another-long-target"}})
+	}
+	if got := FormatProject(markers); len(got) > maxProjectContext {
+		t.Errorf("summary is %d bytes, want at most %d", len(got), maxProjectContext)
+	}
+}
+
+func TestFormatGit(t *testing.T) {
+	if got := FormatGit(nil); got != "" {
+		t.Errorf("expected empty string outside a repository, got %q", got)
+	}
+
+	got := FormatGit(&git.Repo{
+		Branch: "feature", Head: "abc1234", Upstream: "origin/feature", Ahead: 1,
+		Unstaged: 2, Operation: "rebase",
+		Remotes: []git.Remote{{Name: "origin", URL: "git@github.com:acme/app.git"}},
+		Tags:    []string{"v1.2.0", "v1.1.0"},
+	})
+	for _, want := range []string{
+		"Branch: feature",
+		"Upstream: origin/feature, 1 commits ahead and 0 behind",
+		"0 staged, 2 unstaged",
+		"A rebase is in progress",
+		"Remote origin: git@github.com:acme/app.git",
+		"Recent tags: v1.2.0, v1.1.0",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	got = FormatGit(&git.Repo{Branch: "feature", Head: "abc1234", Upstream: "origin/feature", UpstreamGone: true})
+	if !strings.Contains(got, "whether the last commit has been pushed is unknown") || strings.Contains(got, "has been pushed)") {
+		t.Errorf("expected the push state to be unknown:\n%s", got)
+	}
+
+	got = FormatGit(&git.Repo{Head: "abc1234"})
+	for _, want := range []string{"HEAD is detached at abc1234", "no upstream", "Working tree: clean"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatCloud(t *testing.T) {
+	if got := FormatCloud(nil, nil); got != "" {
+		t.Errorf("expected empty string without a cluster or profile, got %q", got)
+	}
+
+	got := FormatCloud(
+		&cloud.Kube{Context: "eks-prod", Cluster: "prod-cluster", Namespace: "payments"},
+		&cloud.AWS{Profile: "dev", Region: "eu-west-1"},
+	)
+	for _, want := range []string{
+		"kubectl context eks-prod (cluster prod-cluster), namespace payments. This looks like production",
+		"AWS profile dev, region eu-west-1\n",
+		"--context/--namespace",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "looks like production") != 1 {
+		t.Errorf("expected only the kubectl context flagged:\n%s", got)
+	}
+}
+
+func TestFormatInput(t *testing.T) {
+	if got := FormatInput("why", nil); got != "why" {
+		t.Errorf("expected the bare question without input, got %q", got)
+	}
+
+	got := FormatInput("why does this fail", &input.Excerpt{Text: "token=[REDACTED]\n```x```\n", Omitted: 10, Redacted: 1})
+	for _, want := range []string{
+		"(10 bytes left out of the middle, 1 secrets replaced with [REDACTED])",
+		"````\ntoken=[REDACTED]\n```x```\n````",
+		"read from stdin",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "\n\nwhy does this fail") {
+		t.Errorf("expected the question last:\n%s", got)
+	}
+
+	if got := FormatInput("what is this", &input.Excerpt{Size: 42, Binary: true}); !strings.Contains(got, "42 bytes of binary data") {
+		t.Errorf("unexpected binary description:\n%s", got)
+	}
+}
+
 func TestFormatMemoryContextEmpty(t *testing.T) {
 	result := FormatMemoryContext(nil)
 	if result != "" {
@@ -91,3 +250,26 @@ func TestFormatMemoryContextWithInteractions(t *testing.T) {
 		t.Error("expected result to contain instruction text")
 	}
 }
+
+func TestReviewPrompt(t *testing.T) {
+	p := ReviewPrompt()
+	if !strings.Contains(p, "PASS") || !strings.Contains(p, "FAIL") {
+		t.Error("ReviewPrompt should ask for a PASS or FAIL verdict")
+	}
+}
+
+func TestFormatReviewFeedback(t *testing.T) {
+	got := FormatReviewFeedback("invented flag", []string{"du has no --depth-human", "not portable"})
+	want := "A reviewer rejected that command: invented flag\n- du has no --depth-human\n- not portable\nSuggest a corrected command."
+	if got != want {
+		t.Errorf("FormatReviewFeedback() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatAnswer(t *testing.T) {
+	got := FormatAnswer("du -sh *", "Show the size of each entry")
+	want := "COMMAND: du -sh *\nEXPLANATION: Show the size of each entry"
+	if got != want {
+		t.Errorf("FormatAnswer() = %q, want %q", got, want)
+	}
+}
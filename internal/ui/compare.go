@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/swibrow/how/internal/llm"
+	"golang.org/x/term"
+)
+
+// Comparison is one provider's answer to a question asked of several.
+type Comparison struct {
+	// Target is the provider name, with ":model" when a model was chosen.
+	Target  string
+	Result  Result
+	Latency time.Duration
+	Usage   llm.Usage
+	Err     error
+}
+
+// DisplayComparison shows the answers side by side, one row per provider.
+// Rows that answered are numbered in the order Pick would list them.
+func DisplayComparison(w io.Writer, rows []Comparison) {
+	width := 100
+	if tw, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && tw > 0 {
+		width = tw
+	}
+	_, _ = fmt.Fprintln(w, renderComparison(rows, width))
+}
+
+// renderComparison renders the table, shrinking it to width only when it
+// would not fit, since the table otherwise stretches to fill it.
+func renderComparison(rows []Comparison, width int) string {
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(explanationStyle).
+		BorderRow(true).
+		Headers("#", "PROVIDER", "ANSWER", "LATENCY", "TOKENS", "WARNINGS")
+
+	n := 0
+	for _, r := range rows {
+		latency := r.Latency.Round(time.Millisecond).String()
+		if r.Err != nil {
+			t.Row("-", r.Target, errorStyle.Render("Error: ")+r.Err.Error(), latency, "-", "")
+			continue
+		}
+		n++
+		t.Row(
+			fmt.Sprint(n),
+			r.Target,
+			comparisonAnswer(r.Result),
+			latency,
+			fmt.Sprintf("%d/%d", r.Usage.InputTokens, r.Usage.OutputTokens),
+			strings.Join(comparisonWarnings(r.Result), "\n"),
+		)
+	}
+
+	t.StyleFunc(func(row, col int) lipgloss.Style {
+		s := lipgloss.NewStyle().Padding(0, 1)
+		if row == table.HeaderRow {
+			return s.Inherit(labelStyle)
+		}
+		return s
+	})
+	out := t.String()
+	if lipgloss.Width(out) > width {
+		out = t.Width(width).String()
+	}
+	return out
+}
+
+// comparisonAnswer is the command with its explanation beneath it.
+func comparisonAnswer(r Result) string {
+	answer := commandStyle.Render(r.Command)
+	if r.Explanation != "" {
+		answer += "\n" + explanationStyle.Render(r.Explanation)
+	}
+	return answer
+}
+
+// comparisonWarnings lists the commands that are not installed, as found
+// by ValidateCommand, followed by the model's own cautions.
+func comparisonWarnings(r Result) []string {
+	var warnings []string
+	if len(r.Missing) > 0 {
+		warnings = append(warnings, "not installed: "+strings.Join(r.Missing, ", "))
+	}
+	return append(warnings, r.Warnings...)
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/swibrow/how/internal/llm"
+)
+
+func TestRenderComparison(t *testing.T) {
+	rows := []Comparison{
+		{Target: "ollama", Err: errors.New("connection refused"), Latency: time.Second},
+		{
+			Target:  "anthropic",
+			Result:  Result{Command: "ss -ltnp", Explanation: "Show listening sockets", Missing: []string{"ss"}},
+			Latency: 1234 * time.Millisecond,
+			Usage:   llm.Usage{InputTokens: 120, OutputTokens: 30},
+		},
+	}
+
+	out := renderComparison(rows, 200)
+	for _, want := range []string{"ss -ltnp", "Show listening sockets", "1.234s", "120/30", "not installed: ss", "connection refused"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table missing %q:\n%s", want, out)
+		}
+	}
+	// The failed row is not numbered, so the answer is 1 as in Pick.
+	if !strings.Contains(out, "│ 1 │ anthropic") {
+		t.Errorf("expected anthropic to be numbered 1:\n%s", out)
+	}
+
+	if w := lipgloss.Width(renderComparison(rows, 60)); w > 60 {
+		t.Errorf("table is %d wide, want at most 60", w)
+	}
+}
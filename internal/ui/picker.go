@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+type pickerAction int
+
+const (
+	pickerNone pickerAction = iota
+	pickerUp
+	pickerDown
+	pickerSelect
+	pickerCancel
+)
+
+// Pick shows the candidates and lets the user choose one with the arrow
+// keys (or j/k, or a digit). It preselects the first candidate whose tools
+// are all installed. Returns false if the user cancelled. When the keyboard
+// is not a terminal the first candidate is returned without prompting.
+func Pick(candidates []Result) (Result, bool, error) {
+	if len(candidates) == 0 {
+		return Result{}, false, nil
+	}
+
+	fd := int(keyboard.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return candidates[0], true, nil
+	}
+	defer term.Restore(fd, oldState) //nolint:errcheck
+
+	selected := firstInstalled(candidates)
+	fmt.Print("\r\n")
+	lines := renderPicker(os.Stdout, candidates, selected)
+
+	var buf [8]byte
+	for {
+		n, err := keyboard.Read(buf[:])
+		if err != nil {
+			return Result{}, false, fmt.Errorf("reading input: %w", err)
+		}
+
+		key := buf[:n]
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			if i := int(key[0] - '1'); i < len(candidates) {
+				selected = i
+				clearLines(os.Stdout, lines)
+				return candidates[selected], true, nil
+			}
+			continue
+		}
+
+		switch pickerKey(key) {
+		case pickerUp:
+			selected = (selected - 1 + len(candidates)) % len(candidates)
+		case pickerDown:
+			selected = (selected + 1) % len(candidates)
+		case pickerSelect:
+			clearLines(os.Stdout, lines)
+			return candidates[selected], true, nil
+		case pickerCancel:
+			clearLines(os.Stdout, lines)
+			return Result{}, false, nil
+		default:
+			continue
+		}
+
+		clearLines(os.Stdout, lines)
+		lines = renderPicker(os.Stdout, candidates, selected)
+	}
+}
+
+// pickerKey maps raw terminal input to a picker action.
+func pickerKey(key []byte) pickerAction {
+	switch string(key) {
+	case "\x1b[A", "\x1bOA", "k":
+		return pickerUp
+	case "\x1b[B", "\x1bOB", "j", "\t":
+		return pickerDown
+	case "\r", "\n":
+		return pickerSelect
+	case "\x1b", "q", "\x03":
+		return pickerCancel
+	default:
+		return pickerNone
+	}
+}
+
+// renderPicker writes the candidate list with the selected entry marked and
+// returns the number of lines written. It uses \r\n because the terminal
+// is in raw mode.
+func renderPicker(w io.Writer, candidates []Result, selected int) int {
+	lines := 0
+	_, _ = fmt.Fprintf(w, "  %s\r\n", explanationStyle.Render("↑/↓ to choose, enter to select, q to cancel"))
+	lines++
+
+	for i, c := range candidates {
+		marker := " "
+		cmd := c.Command
+		if i == selected {
+			marker = labelStyle.Render("›")
+			cmd = commandStyle.Render(cmd)
+		}
+		_, _ = fmt.Fprintf(w, "  %s %d. %s%s\r\n", marker, i+1, cmd, missingNote(c.Missing))
+		lines++
+		if c.Explanation != "" {
+			_, _ = fmt.Fprintf(w, "       %s\r\n", explanationStyle.Render(c.Explanation))
+			lines++
+		}
+	}
+	return lines
+}
+
+// clearLines moves the cursor up over n previously written lines and
+// erases them.
+func clearLines(w io.Writer, n int) {
+	_, _ = fmt.Fprintf(w, "\033[%dA\r\033[J", n)
+}
+
+// firstInstalled returns the index of the first candidate with no missing
+// commands, or 0 if every candidate is missing something.
+func firstInstalled(candidates []Result) int {
+	for i, c := range candidates {
+		if len(c.Missing) == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// missingNote renders a short suffix naming commands that are not installed.
+func missingNote(missing []string) string {
+	if len(missing) == 0 {
+		return ""
+	}
+	return "  " + hintStyle.Render("(not installed: "+strings.Join(missing, ", ")+")")
+}
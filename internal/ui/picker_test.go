@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPickerKey(t *testing.T) {
+	cases := []struct {
+		name string
+		key  string
+		want pickerAction
+	}{
+		{name: "arrow up", key: "\x1b[A", want: pickerUp},
+		{name: "arrow down", key: "\x1b[B", want: pickerDown},
+		{name: "application mode down", key: "\x1bOB", want: pickerDown},
+		{name: "k", key: "k", want: pickerUp},
+		{name: "j", key: "j", want: pickerDown},
+		{name: "enter", key: "\r", want: pickerSelect},
+		{name: "escape", key: "\x1b", want: pickerCancel},
+		{name: "ctrl-c", key: "\x03", want: pickerCancel},
+		{name: "other", key: "x", want: pickerNone},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pickerKey([]byte(tc.key)); got != tc.want {
+				t.Errorf("pickerKey(%q) = %v, want %v", tc.key, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderPicker(t *testing.T) {
+	candidates := []Result{
+		{Command: "fd -e go", Explanation: "Uses fd", Missing: []string{"fd"}},
+		{Command: "find . -name '*.go'"},
+	}
+
+	var buf bytes.Buffer
+	lines := renderPicker(&buf, candidates, 1)
+
+	// header + 2 commands + 1 explanation
+	if lines != 4 {
+		t.Errorf("expected 4 lines, got %d", lines)
+	}
+	output := buf.String()
+	if strings.Count(output, "\r\n") != lines {
+		t.Errorf("expected %d raw-mode line endings, got: %q", lines, output)
+	}
+	if !strings.Contains(output, "not installed: fd") {
+		t.Errorf("expected missing tool note, got: %q", output)
+	}
+	if !strings.Contains(output, "2. ") {
+		t.Errorf("expected numbered entries, got: %q", output)
+	}
+}
+
+func TestFirstInstalled(t *testing.T) {
+	candidates := []Result{
+		{Command: "fd", Missing: []string{"fd"}},
+		{Command: "find"},
+	}
+	if got := firstInstalled(candidates); got != 1 {
+		t.Errorf("firstInstalled = %d, want 1", got)
+	}
+
+	allMissing := []Result{{Missing: []string{"a"}}, {Missing: []string{"b"}}}
+	if got := firstInstalled(allMissing); got != 0 {
+		t.Errorf("firstInstalled with all missing = %d, want 0", got)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	result := Result{
+		Command:      "ss -tlnp",
+		Alternatives: []Result{{Command: "lsof -i -P"}, {Command: "netstat -tlnp"}},
+	}
+
+	candidates := result.Candidates()
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+	if candidates[0].Command != "ss -tlnp" || candidates[0].Alternatives != nil {
+		t.Errorf("first candidate should be the primary command without alternatives, got %+v", candidates[0])
+	}
+	if candidates[2].Command != "netstat -tlnp" {
+		t.Errorf("last candidate: got %q", candidates[2].Command)
+	}
+}
+
+func TestResultValidate(t *testing.T) {
+	result := Result{
+		Command:      "ls -la",
+		Alternatives: []Result{{Command: "nonexistent_cmd_xyz123 -la"}},
+	}
+	result.Validate()
+
+	if len(result.Missing) != 0 {
+		t.Errorf("expected nothing missing for ls, got %v", result.Missing)
+	}
+	if len(result.Alternatives[0].Missing) != 1 {
+		t.Errorf("expected alternative to report missing command, got %v", result.Alternatives[0].Missing)
+	}
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ProgressBar renders a single-line, redrawn progress bar, e.g. for model
+// downloads.
+type ProgressBar struct {
+	out   io.Writer
+	label string
+	width int
+	drawn bool
+}
+
+// NewProgressBar returns a progress bar labelled label, writing to out.
+func NewProgressBar(out io.Writer, label string) *ProgressBar {
+	return &ProgressBar{out: out, label: label, width: 30}
+}
+
+// Update redraws the bar. When total is unknown (zero) only the status is
+// shown.
+func (p *ProgressBar) Update(status string, completed, total int64) {
+	p.drawn = true
+	if total <= 0 {
+		_, _ = fmt.Fprintf(p.out, "\r\033[K  %s %s", labelStyle.Render(p.label), explanationStyle.Render(status))
+		return
+	}
+
+	completed = min(max(completed, 0), total)
+	filled := int(int64(p.width) * completed / total)
+	bar := commandStyle.Render(strings.Repeat("█", filled)) + explanationStyle.Render(strings.Repeat("░", p.width-filled))
+	_, _ = fmt.Fprintf(p.out, "\r\033[K  %s %s %3d%%  %s/%s",
+		labelStyle.Render(p.label), bar, completed*100/total, formatBytes(completed), formatBytes(total))
+}
+
+// Finish ends the bar's line.
+func (p *ProgressBar) Finish() {
+	if p.drawn {
+		_, _ = fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 GB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
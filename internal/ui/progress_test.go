@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 512, want: "512 B"},
+		{n: 1536, want: "1.5 KB"},
+		{n: 4 * 1024 * 1024 * 1024, want: "4.0 GB"},
+	}
+	for _, tc := range cases {
+		if got := formatBytes(tc.n); got != tc.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := NewProgressBar(&buf, "pulling llama3")
+
+	bar.Update("pulling manifest", 0, 0)
+	if !strings.Contains(buf.String(), "pulling manifest") {
+		t.Errorf("expected status without total, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	bar.Update("pulling abc", 512, 1024)
+	output := buf.String()
+	if !strings.Contains(output, " 50%") {
+		t.Errorf("expected percentage, got: %q", output)
+	}
+	if !strings.Contains(output, "512 B/1.0 KB") {
+		t.Errorf("expected byte counts, got: %q", output)
+	}
+
+	buf.Reset()
+	bar.Finish()
+	if buf.String() != "\n" {
+		t.Errorf("Finish should end the line, got: %q", buf.String())
+	}
+}
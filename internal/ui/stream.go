@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// IsInteractive reports whether stdout is a terminal, i.e. whether live
+// redrawing output is appropriate.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// StreamRenderer shows the command as a streamed response arrives. It keeps
+// redrawing a single line with the command parsed from the text received so
+// far; Finish clears that line so Display can print the final result.
+type StreamRenderer struct {
+	out     io.Writer
+	width   int
+	text    strings.Builder
+	last    string
+	started bool
+}
+
+// NewStreamRenderer returns a renderer writing to out.
+func NewStreamRenderer(out io.Writer) *StreamRenderer {
+	width := 80
+	if f, ok := out.(*os.File); ok {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+	return &StreamRenderer{out: out, width: width}
+}
+
+// Write appends a chunk of the response and redraws the command line.
+// Its signature matches the onChunk callback of llm.StreamingProvider.
+func (r *StreamRenderer) Write(chunk string) {
+	r.text.WriteString(chunk)
+
+	cmd := partialCommand(r.text.String())
+	if cmd == "" || cmd == r.last {
+		return
+	}
+	r.last = cmd
+
+	if !r.started {
+		// Mirror the leading blank line printed by Display.
+		_, _ = fmt.Fprintln(r.out)
+		r.started = true
+	}
+
+	// Keep the line within the terminal width so \r can clear it.
+	if limit := r.width - 5; limit > 0 {
+		if runes := []rune(cmd); len(runes) > limit {
+			cmd = "…" + string(runes[len(runes)-limit+1:])
+		}
+	}
+	_, _ = fmt.Fprintf(r.out, "\r\033[K  %s %s", labelStyle.Render("$"), explanationStyle.Render(cmd))
+}
+
+// Text returns everything received so far.
+func (r *StreamRenderer) Text() string {
+	return r.text.String()
+}
+
+// Finish erases the live line and the blank line above it, leaving the
+// cursor where Display expects to start.
+func (r *StreamRenderer) Finish() {
+	if !r.started {
+		return
+	}
+	_, _ = fmt.Fprint(r.out, "\r\033[K\033[1A")
+	r.started = false
+}
+
+// jsonCommandRe matches the (possibly unterminated) "command" string of a
+// structured response.
+var jsonCommandRe = regexp.MustCompile(`"command"\s*:\s*"((?:[^"\\]|\\.)*)`)
+
+// partialCommand extracts the command from an incomplete response, either
+// structured JSON or COMMAND:/EXPLANATION: text. For text it waits until
+// the output can no longer turn out to be a COMMAND: prefix so that
+// half-received labels are never shown as commands.
+func partialCommand(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") {
+		m := jsonCommandRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			return ""
+		}
+		// The match never ends in a dangling backslash, but a partially
+		// received \u escape can still fail to unquote.
+		if cmd, err := strconv.Unquote(`"` + m[1] + `"`); err == nil {
+			return cmd
+		}
+		return m[1]
+	}
+	if trimmed == "" || strings.HasPrefix("COMMAND:", trimmed) {
+		return ""
+	}
+	return ParseResponse(text).Command
+}
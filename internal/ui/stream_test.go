@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPartialCommand(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "empty", text: "", want: ""},
+		{name: "partial label", text: "COMM", want: ""},
+		{name: "label only", text: "COMMAND:", want: ""},
+		{name: "partial command", text: "COMMAND: ls -", want: "ls -"},
+		{name: "explanation started", text: "COMMAND: ls -la\nEXPLA", want: "ls -la"},
+		{name: "no prefix", text: "git sta", want: "git sta"},
+		{name: "json before command", text: `{"comm`, want: ""},
+		{name: "json partial command", text: `{"command": "grep -r \"TODO`, want: `grep -r "TODO`},
+		{name: "json complete", text: `{"command":"ls","explanation":"x"}`, want: "ls"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := partialCommand(tc.text); got != tc.want {
+				t.Errorf("partialCommand(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStreamRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewStreamRenderer(&buf)
+
+	for _, chunk := range []string{"COMM", "AND: git ", "status\n", "EXPLANATION: Show the working tree"} {
+		r.Write(chunk)
+	}
+
+	if got, want := r.Text(), "COMMAND: git status\nEXPLANATION: Show the working tree"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "git status") {
+		t.Errorf("expected streamed command in output, got: %q", output)
+	}
+	if strings.Contains(output, "COMM") {
+		t.Errorf("partial label should not be rendered, got: %q", output)
+	}
+
+	buf.Reset()
+	r.Finish()
+	if !strings.Contains(buf.String(), "\033[K") {
+		t.Errorf("Finish should clear the live line, got: %q", buf.String())
+	}
+}
+
+func TestStreamRendererFinishWithoutOutput(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewStreamRenderer(&buf)
+	r.Finish()
+	if buf.Len() != 0 {
+		t.Errorf("expected no output when nothing was rendered, got: %q", buf.String())
+	}
+}
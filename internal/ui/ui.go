@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/swibrow/how/internal/llm"
 	"golang.org/x/term"
 )
 
@@ -26,11 +28,90 @@ var (
 )
 
 type Result struct {
-	Command     string
-	Explanation string
+	Command      string
+	Explanation  string
+	Warnings     []string
+	Alternatives []Result
+	// Missing lists commands used by Command that are not installed,
+	// as reported by ValidateCommand.
+	Missing []string
+	// Provider names the backend that answered, when a fallback chain
+	// picked one.
+	Provider string
+	// Cached is set when the answer came from the response cache.
+	Cached bool
+	// Verdict is the reviewer's critique when --verify is used.
+	Verdict *Verdict
+	// Production describes the production-looking context, such as a
+	// kubectl context, that the command would act on.
+	Production string
 }
 
-// ParseResponse extracts command and explanation from the LLM response.
+// Verdict is a reviewer's critique of a suggested command.
+type Verdict struct {
+	Passed   bool
+	Critique string
+	Issues   []string
+}
+
+// NewVerdict reads a review response. The reviewer answers in the command
+// format with PASS or FAIL in place of the command; anything else counts
+// as a failure.
+func NewVerdict(resp *llm.Response) Verdict {
+	r := NewResult(resp)
+	return Verdict{
+		Passed:   strings.EqualFold(strings.Trim(r.Command, " .:"), "PASS"),
+		Critique: r.Explanation,
+		Issues:   r.Warnings,
+	}
+}
+
+// NewResult converts a provider response into a Result. Structured
+// responses are used as-is; unstructured text goes through ParseResponse.
+func NewResult(resp *llm.Response) Result {
+	if resp.Command == "" {
+		result := ParseResponse(resp.Text)
+		result.Provider = resp.Provider
+		result.Cached = resp.Cached
+		return result
+	}
+
+	result := Result{
+		Command:     stripBackticks(resp.Command),
+		Explanation: resp.Explanation,
+		Warnings:    resp.Warnings,
+		Provider:    resp.Provider,
+		Cached:      resp.Cached,
+	}
+	for _, alt := range resp.Alternatives {
+		result.Alternatives = append(result.Alternatives, Result{
+			Command:     stripBackticks(alt.Command),
+			Explanation: alt.Explanation,
+			Provider:    resp.Provider,
+			Cached:      resp.Cached,
+		})
+	}
+	return result
+}
+
+// Validate fills Missing for the command and each alternative.
+func (r *Result) Validate() {
+	r.Missing = ValidateCommand(r.Command)
+	for i := range r.Alternatives {
+		r.Alternatives[i].Validate()
+	}
+}
+
+// Candidates returns the primary command followed by its alternatives as a
+// flat list, suitable for Pick.
+func (r Result) Candidates() []Result {
+	primary := r
+	primary.Alternatives = nil
+	return append([]Result{primary}, r.Alternatives...)
+}
+
+// ParseResponse extracts command and explanation from a plain-text LLM
+// response. It is the fallback for models without structured output.
 func ParseResponse(response string) Result {
 	var result Result
 
@@ -88,46 +169,244 @@ func Display(result Result) {
 	if result.Explanation != "" {
 		fmt.Printf("  %s\n", explanationStyle.Render(result.Explanation))
 	}
+	if result.Production != "" {
+		fmt.Printf("  %s %s\n", productionStyle.Render(" PRODUCTION "), result.Production)
+	}
+	for _, w := range result.Warnings {
+		fmt.Printf("  %s %s\n", hintStyle.Render("Caution:"), w)
+	}
+	if v := result.Verdict; v != nil {
+		displayVerdict(*v)
+	}
+	if source := resultSource(result); source != "" {
+		fmt.Printf("  %s\n", explanationStyle.Render(source))
+	}
+	if len(result.Alternatives) > 0 {
+		fmt.Printf("\n  %s\n", labelStyle.Render("Alternatives:"))
+		for _, alt := range result.Alternatives {
+			fmt.Printf("    %s %s%s\n", labelStyle.Render("$"), alt.Command, missingNote(alt.Missing))
+			if alt.Explanation != "" {
+				fmt.Printf("    %s\n", explanationStyle.Render(alt.Explanation))
+			}
+		}
+	}
 	fmt.Println()
 }
 
+func displayVerdict(v Verdict) {
+	label := commandStyle.Render("Verified:")
+	if !v.Passed {
+		label = errorStyle.Render("Review failed:")
+	}
+	fmt.Printf("  %s %s\n", label, v.Critique)
+	for _, issue := range v.Issues {
+		fmt.Printf("    - %s\n", issue)
+	}
+}
+
+// resultSource describes where an answer came from, e.g. "cached" or
+// "answered by ollama".
+func resultSource(result Result) string {
+	var parts []string
+	if result.Cached {
+		parts = append(parts, "cached")
+	}
+	if result.Provider != "" {
+		parts = append(parts, "answered by "+result.Provider)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // DisplayQuiet shows only the command (for piping).
 func DisplayQuiet(result Result) {
 	fmt.Println(result.Command)
 }
 
+// DisplayModels lists model IDs, marking the configured one. An Ollama
+// model configured without a tag matches its :latest variant.
+func DisplayModels(models []string, current string) {
+	if len(models) == 0 {
+		fmt.Println("  No models found.")
+		return
+	}
+	for _, m := range models {
+		if m == current || strings.TrimSuffix(m, ":latest") == current {
+			fmt.Printf("  %s %s %s\n", labelStyle.Render("*"), commandStyle.Render(m), explanationStyle.Render("(configured)"))
+			continue
+		}
+		fmt.Printf("    %s\n", m)
+	}
+}
+
 // DisplayError shows a formatted error message.
 func DisplayError(msg string) {
 	fmt.Fprintf(os.Stderr, "\n  %s %s\n\n", errorStyle.Render("Error:"), msg)
 }
 
+// Choice is the user's answer to the confirmation prompt.
+type Choice int
+
+const (
+	ChoiceDecline Choice = iota
+	ChoiceRun
+	ChoiceRefine
+)
+
+// apiKeyEnv names the environment variable holding each provider's key.
+var apiKeyEnv = map[string]string{
+	"anthropic": "ANTHROPIC_API_KEY",
+	"openai":    "OPENAI_API_KEY",
+	"gemini":    "GEMINI_API_KEY",
+}
+
+// DisplayProviderError shows a failed LLM request with an actionable hint
+// for classified provider errors.
+func DisplayProviderError(err error) {
+	DisplayError(fmt.Sprintf("LLM request failed: %v", err))
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "  %s %s\n\n", hintStyle.Render("Hint:"), hint)
+	}
+}
+
+// errorHint returns a suggestion for fixing a provider error, or "" if
+// there is nothing specific to suggest.
+func errorHint(err error) string {
+	perr := providerError(err)
+	if perr == nil {
+		return ""
+	}
+
+	switch perr.Kind {
+	case llm.ErrAuth:
+		if perr.Credential != "" {
+			return fmt.Sprintf("%s rejected the credentials, check %s", perr.Provider, perr.Credential)
+		}
+		if env, ok := apiKeyEnv[perr.Provider]; ok {
+			return fmt.Sprintf("your API key is invalid, check %s or %s.api_key in ~/.config/how/config.yaml", env, perr.Provider)
+		}
+		return fmt.Sprintf("%s rejected the credentials, check your %s configuration", perr.Provider, perr.Provider)
+	case llm.ErrRateLimit:
+		msg := fmt.Sprintf("%s is rate limiting requests", perr.Provider)
+		if perr.RetryAfter > 0 {
+			msg += fmt.Sprintf(", try again in %s", perr.RetryAfter.Round(time.Second))
+		}
+		return msg + " or add a fallback under providers: in the config"
+	case llm.ErrNetwork:
+		if perr.Provider == "ollama" {
+			return "could not reach Ollama, check that it is running (ollama serve) and ollama.url is correct"
+		}
+		return fmt.Sprintf("could not reach %s, check your network connection or VPN", perr.Provider)
+	case llm.ErrTimeout:
+		return fmt.Sprintf("%s did not answer in time, raise timeout (or %s.timeout) in the config", perr.Provider, perr.Provider)
+	case llm.ErrBadRequest:
+		if perr.Provider == "replay" {
+			return "the question is not in the cassette, record it first with --record"
+		}
+		return fmt.Sprintf("%s rejected the request, check that %s.model names a valid model", perr.Provider, perr.Provider)
+	case llm.ErrServer:
+		return fmt.Sprintf("%s is having problems, try again shortly", perr.Provider)
+	default:
+		return ""
+	}
+}
+
+// providerError finds the provider error behind err. In a joined error,
+// such as a fallback chain that ran out of providers, it takes the last
+// one, which ended the chain.
+func providerError(err error) *llm.Error {
+	switch e := err.(type) {
+	case *llm.Error:
+		return e
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for i := len(errs) - 1; i >= 0; i-- {
+			if perr := providerError(errs[i]); perr != nil {
+				return perr
+			}
+		}
+	case interface{ Unwrap() error }:
+		return providerError(e.Unwrap())
+	}
+	return nil
+}
+
 // ConfirmAndRun prompts the user to run the command and executes it.
-// Returns (true, nil) if confirmed and succeeded, (true, err) if confirmed
-// but the command failed, and (false, nil) if the user declined.
-func ConfirmAndRun(command string) (bool, error) {
-	fmt.Printf("  Run this command? [y/N] ")
+// Returns (ChoiceRun, nil) if confirmed and succeeded, (ChoiceRun, err) if
+// confirmed but the command failed, (ChoiceRefine, nil) if the user wants to
+// correct the suggestion, and (ChoiceDecline, nil) if the user declined.
+func ConfirmAndRun(command string) (Choice, error) {
+	fmt.Printf("  Run this command? [y/N/r=refine] ")
+
+	key, err := readKey()
+	if err != nil {
+		return ChoiceDecline, err
+	}
 
-	fd := int(os.Stdin.Fd())
+	switch key {
+	case 'y', 'Y':
+		return ChoiceRun, RunCommand(command)
+	case 'r', 'R':
+		return ChoiceRefine, nil
+	default:
+		return ChoiceDecline, nil
+	}
+}
+
+// Confirm asks a yes/no question and reports whether the user pressed y.
+func Confirm(question string) (bool, error) {
+	fmt.Printf("  %s [y/N] ", question)
+
+	key, err := readKey()
+	if err != nil {
+		return false, err
+	}
+	return key == 'y' || key == 'Y', nil
+}
+
+// keyboard is where prompts read the user's answers from.
+var keyboard = os.Stdin
+
+// OpenTerminal makes prompts read from the controlling terminal instead of
+// stdin, for when input has been piped into how. It does nothing if there
+// is no terminal, in which case prompts are declined as before.
+func OpenTerminal() {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		keyboard = tty
+	}
+}
+
+// readKey reads a single keypress in raw mode and moves to the next line.
+// It returns 0 without error when the keyboard is not a terminal.
+func readKey() (byte, error) {
+	fd := int(keyboard.Fd())
 	oldState, err := term.MakeRaw(fd)
 	if err != nil {
 		// Not a terminal (e.g. piped input) — can't use raw mode
-		return false, nil
+		return 0, nil
 	}
 
 	var buf [1]byte
-	_, err = os.Stdin.Read(buf[:])
+	_, err = keyboard.Read(buf[:])
 	_ = term.Restore(fd, oldState)
 	fmt.Println() // move to next line after the keypress
 
 	if err != nil {
-		return false, fmt.Errorf("reading input: %w", err)
+		return 0, fmt.Errorf("reading input: %w", err)
 	}
+	return buf[0], nil
+}
 
-	if buf[0] != 'y' && buf[0] != 'Y' {
-		return false, nil
-	}
+// ReadRefinement asks for a follow-up correction to the last suggestion,
+// such as "use rg instead". Returns an empty string if the user entered
+// nothing.
+func ReadRefinement() (string, error) {
+	fmt.Printf("  %s ", labelStyle.Render("Refine:"))
 
-	return true, RunCommand(command)
+	line, err := bufio.NewReader(keyboard).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
 }
 
 // RunCommand executes a command via the shell.
@@ -231,6 +510,8 @@ var zshExtendedRe = regexp.MustCompile(`(?m)^: \d+:\d+;`)
 
 var (
 	hintStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#f9e2af")) // Yellow
+	// Base on Red, so production stands out even in a busy terminal.
+	productionStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#1e1e2e")).Background(lipgloss.Color("#f38ba8"))
 
 	// Matches patterns like "sh: ss: command not found" or "bash: ss: command not found"
 	notFoundRe = regexp.MustCompile(`(?:sh|bash):\s*(?:line \d+:\s*)?(\S+):\s*(?:command )?not found`)
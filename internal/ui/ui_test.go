@@ -2,12 +2,16 @@ package ui
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/swibrow/how/internal/llm"
 )
 
 func TestParseResponse(t *testing.T) {
@@ -125,6 +129,39 @@ func TestParseResponseStripsBackticks(t *testing.T) {
 	}
 }
 
+func TestNewResultStructured(t *testing.T) {
+	resp := &llm.Response{
+		Command:      "`ss -tlnp`",
+		Explanation:  "List listening ports",
+		Warnings:     []string{"Needs root to show process names"},
+		Alternatives: []llm.Alternative{{Command: "lsof -i -P", Explanation: "Uses lsof"}},
+		Text:         "{}",
+	}
+	result := NewResult(resp)
+
+	if result.Command != "ss -tlnp" {
+		t.Errorf("command: got %q, want %q", result.Command, "ss -tlnp")
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings: got %v", result.Warnings)
+	}
+	if len(result.Alternatives) != 1 || result.Alternatives[0].Command != "lsof -i -P" {
+		t.Errorf("alternatives: got %+v", result.Alternatives)
+	}
+}
+
+func TestNewResultFallsBackToText(t *testing.T) {
+	resp := &llm.Response{Text: "COMMAND: ls -la\nEXPLANATION: List files"}
+	result := NewResult(resp)
+
+	if result.Command != "ls -la" {
+		t.Errorf("command: got %q, want %q", result.Command, "ls -la")
+	}
+	if result.Explanation != "List files" {
+		t.Errorf("explanation: got %q, want %q", result.Explanation, "List files")
+	}
+}
+
 func TestDisplayQuiet(t *testing.T) {
 	result := Result{
 		Command:     "echo hello",
@@ -436,3 +473,79 @@ func TestRunCommandNotFound(t *testing.T) {
 		t.Errorf("expected 'not installed' hint in stderr, got: %q", output)
 	}
 }
+
+func TestErrorHint(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "auth", err: &llm.Error{Kind: llm.ErrAuth, Provider: "anthropic"}, want: "ANTHROPIC_API_KEY"},
+		{name: "auth credential", err: &llm.Error{Kind: llm.ErrAuth, Provider: "azure", Credential: "azure.token_command"}, want: "check azure.token_command"},
+		{name: "rate limit", err: &llm.Error{Kind: llm.ErrRateLimit, Provider: "openai", RetryAfter: 20 * time.Second}, want: "try again in 20s"},
+		{name: "ollama down", err: &llm.Error{Kind: llm.ErrNetwork, Provider: "ollama"}, want: "ollama serve"},
+		{name: "timeout", err: &llm.Error{Kind: llm.ErrTimeout, Provider: "openai"}, want: "openai.timeout"},
+		{name: "bad request", err: &llm.Error{Kind: llm.ErrBadRequest, Provider: "anthropic"}, want: "anthropic.model"},
+		{name: "replay miss", err: &llm.Error{Kind: llm.ErrBadRequest, Provider: "replay"}, want: "--record"},
+		{name: "wrapped", err: fmt.Errorf("a: %w", &llm.Error{Kind: llm.ErrServer, Provider: "openai"}), want: "try again"},
+		{name: "fallback chain", err: fmt.Errorf("all failed: %w", errors.Join(
+			&llm.Error{Kind: llm.ErrAuth, Provider: "anthropic"},
+			errors.New("boom"),
+			&llm.Error{Kind: llm.ErrNetwork, Provider: "ollama"},
+		)), want: "ollama serve"},
+		{name: "untyped", err: errors.New("boom"), want: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := errorHint(tc.err)
+			if tc.want == "" {
+				if got != "" {
+					t.Errorf("expected no hint, got %q", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tc.want) {
+				t.Errorf("errorHint() = %q, want it to contain %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *llm.Response
+		want Verdict
+	}{
+		{
+			name: "structured pass",
+			resp: &llm.Response{Command: "PASS", Explanation: "Correct"},
+			want: Verdict{Passed: true, Critique: "Correct"},
+		},
+		{
+			name: "structured fail with issues",
+			resp: &llm.Response{Command: "FAIL", Explanation: "Invented flag", Warnings: []string{"ls has no --size-sort"}},
+			want: Verdict{Critique: "Invented flag", Issues: []string{"ls has no --size-sort"}},
+		},
+		{
+			name: "text pass",
+			resp: &llm.Response{Text: "COMMAND: pass.\nEXPLANATION: Looks right"},
+			want: Verdict{Passed: true, Critique: "Looks right"},
+		},
+		{
+			name: "unparseable counts as failure",
+			resp: &llm.Response{Text: "I think it is probably fine"},
+			want: Verdict{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewVerdict(tt.resp)
+			if got.Passed != tt.want.Passed || got.Critique != tt.want.Critique || len(got.Issues) != len(tt.want.Issues) {
+				t.Errorf("NewVerdict() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}